@@ -0,0 +1,92 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// tarGz 将目录打包为 tar.gz 写入 w(目录结构与 com.TarGz 相同)，写入的同时计算压缩包的校验和(返回值中的 File 为空)。
+// threads 为并行压缩的线程数
+func tarGz(ctx context.Context, w io.Writer, srcDir string, level int, threads int, algos []string) (fileChecksum, error) {
+	cw := newChecksumWriter(w, algos)
+	gw, err := newGzipWriter(cw, level, threads)
+	if err != nil {
+		return fileChecksum{}, err
+	}
+	tw := tar.NewWriter(gw)
+	if err = tarDir(ctx, tw, srcDir, `.`); err != nil {
+		return fileChecksum{}, err
+	}
+	if err = tw.Close(); err != nil {
+		return fileChecksum{}, err
+	}
+	if err = gw.Close(); err != nil {
+		return fileChecksum{}, err
+	}
+	return cw.checksum(``), nil
+}
+
+// tarDir 按文件名顺序写入目录中的文件，子目录在其中的文件之后写入
+func tarDir(ctx context.Context, tw *tar.Writer, dir string, recPath string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		fpath := filepath.Join(dir, entry.Name())
+		name := recPath + `/` + entry.Name()
+		fi, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if err = tarDir(ctx, tw, fpath, name); err != nil {
+				return err
+			}
+			name += `/`
+		}
+		if err = tarFile(ctx, tw, fpath, name, fi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tarFile(ctx context.Context, tw *tar.Writer, fpath string, name string, fi os.FileInfo) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(fi.Mode()),
+		ModTime: fi.ModTime(),
+	}
+	if fi.IsDir() {
+		hdr.Typeflag = tar.TypeDir
+		return tw.WriteHeader(hdr)
+	}
+	hdr.Size = fi.Size()
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	f, err := os.Open(fpath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, contextReader{ctx: ctx, r: f})
+	return err
+}
+
+// contextReader ctx 被取消后停止读取
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(b []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, context.Cause(r.ctx)
+	}
+	return r.r.Read(b)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTarGz(t *testing.T) {
+	srcDir := filepath.Join(t.TempDir(), `nging_linux_amd64`)
+	writeTestFiles(t, srcDir, map[string]string{
+		`nging`:         `binary`,
+		`config/ua.txt`: `ua`,
+		`startup`:       `startup`,
+	})
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, `data/logs`), os.ModePerm))
+	destFile := filepath.Join(t.TempDir(), `nging_linux_amd64.tar.gz`)
+	algos := []string{`sha256`, `sha512`}
+	f, err := createAtomic(destFile)
+	assert.NoError(t, err)
+	sum, err := tarGz(context.Background(), f, srcDir, gzip.BestCompression, 0, algos)
+	assert.NoError(t, err)
+	assert.NoError(t, f.commit())
+	sum.File = destFile
+
+	// 写入时计算的校验和与重新读取的结果一致
+	expected, err := checksumFile(destFile, algos)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, sum)
+
+	r, err := os.Open(destFile)
+	assert.NoError(t, err)
+	defer r.Close()
+	gr, err := gzip.NewReader(r)
+	assert.NoError(t, err)
+	tr := tar.NewReader(gr)
+	var names []string
+	contents := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, hdr.Name)
+		b, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		contents[hdr.Name] = string(b)
+	}
+	assert.Equal(t, []string{`./config/ua.txt`, `./config/`, `./data/logs/`, `./data/`, `./nging`, `./startup`}, names)
+	assert.Equal(t, `binary`, contents[`./nging`])
+	assert.Equal(t, `ua`, contents[`./config/ua.txt`])
+}
+
+func TestTarGzCanceled(t *testing.T) {
+	srcDir := t.TempDir()
+	writeTestFiles(t, srcDir, map[string]string{`nging`: `binary`})
+	destFile := filepath.Join(t.TempDir(), `nging_linux_amd64.tar.gz`)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f, err := createAtomic(destFile)
+	assert.NoError(t, err)
+	_, err = tarGz(ctx, f, srcDir, gzip.BestCompression, 0, []string{`sha256`})
+	assert.ErrorIs(t, err, context.Canceled)
+	f.abort()
+	entries, err := os.ReadDir(filepath.Dir(destFile))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
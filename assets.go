@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// assetFile 需要打包的资源文件
+type assetFile struct {
+	Source string // 文件路径(相对于项目目录，使用 `/` 分隔)
+	Name   string // 去除前缀后的资源路径，例如: template/common/header.html
+}
+
+// compileAssetIgnores 编译忽略规则(BindataIgnore 及 Bindata.Ignore 默认规则)
+func compileAssetIgnores(bindataIgnore []string) ([]*regexp.Regexp, error) {
+	patterns := make([]string, 0, len(bindataIgnore)+1)
+	patterns = append(patterns, bindataIgnore...)
+	patterns = append(patterns, p.Bindata.WithDefaults().Ignore)
+	ignores := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		ignores = append(ignores, re)
+	}
+	return ignores, nil
+}
+
+// assetPrefix 返回资源文件路径匹配的前缀，没有匹配时返回空字符串
+func assetPrefix(source string, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(source, prefix) {
+			return prefix
+		}
+	}
+	return ``
+}
+
+// assetName 去除资源文件路径的前缀
+func assetName(source string, prefixes []string) string {
+	return strings.TrimPrefix(source, assetPrefix(source, prefixes))
+}
+
+// collectAssetFiles 遍历资源目录，返回排除忽略规则后的所有资源文件。
+// miscDirs 与 prefixes 为 buildGoGenerateCommandData 的返回值
+func collectAssetFiles(projectPath string, prefixes []string, miscDirs []string, bindataIgnore []string) ([]assetFile, error) {
+	ignores, err := compileAssetIgnores(bindataIgnore)
+	if err != nil {
+		return nil, err
+	}
+	var files []assetFile
+	for _, miscDir := range miscDirs {
+		dir := strings.TrimSuffix(strings.TrimSuffix(miscDir, `...`), `/`)
+		root := filepath.FromSlash(dir)
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(projectPath, root)
+		}
+		err = filepath.WalkDir(root, func(fpath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if fpath == root && errors.Is(err, fs.ErrNotExist) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, fpath)
+			if err != nil {
+				return err
+			}
+			source := path.Join(dir, filepath.ToSlash(rel))
+			for _, re := range ignores {
+				if re.MatchString(source) {
+					return nil
+				}
+			}
+			files = append(files, assetFile{Source: source, Name: assetName(source, prefixes)})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// assetCollision 多个源文件对应相同的资源路径
+type assetCollision struct {
+	Name    string   // 资源路径
+	Sources []string // 源文件路径
+}
+
+// findAssetCollisions 查找去除前缀后资源路径相同的文件，allowed 中匹配的资源路径除外
+func findAssetCollisions(files []assetFile, allowed []string) []assetCollision {
+	sources := map[string][]string{}
+	var names []string
+	for _, file := range files {
+		if _, ok := sources[file.Name]; !ok {
+			names = append(names, file.Name)
+		}
+		if !slices.Contains(sources[file.Name], file.Source) {
+			sources[file.Name] = append(sources[file.Name], file.Source)
+		}
+	}
+	var collisions []assetCollision
+	for _, name := range names {
+		if len(sources[name]) < 2 || isAllowedAssetCollision(name, allowed) {
+			continue
+		}
+		collisions = append(collisions, assetCollision{Name: name, Sources: sources[name]})
+	}
+	return collisions
+}
+
+func isAllowedAssetCollision(name string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if pattern == name {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// assetCollisionError 返回列出所有冲突资源路径及其源文件的错误
+func assetCollisionError(osName string, collisions []assetCollision) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "found %d embedded asset path collision(s) for VendorMiscDirs key %q (add them to AllowAssetCollisions to ignore):", len(collisions), osName)
+	for _, collision := range collisions {
+		b.WriteString("\n  " + collision.Name)
+		for _, source := range collision.Sources {
+			b.WriteString("\n    <= " + source)
+		}
+	}
+	return errors.New(b.String())
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(file), os.ModePerm))
+		assert.NoError(t, os.WriteFile(file, []byte(content), 0666))
+	}
+}
+
+func TestCollectAssetFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`template/index.html`:                                       `index`,
+		`template/combined/all.html`:                                `combined`,
+		`public/assets/style.less`:                                  `less`,
+		`public/assets/style.css`:                                   `css`,
+		`vendor/github.com/nging-plugins/collector/template/a.html`: `a`,
+	})
+	prefixes, miscDirs := buildGoGenerateCommandData(context.Background(), []string{
+		`vendor/github.com/nging-plugins/collector/template/`,
+		`public/assets/`,
+		`template/`,
+		`config/i18n/`,
+	})
+	files, err := collectAssetFiles(root, prefixes, miscDirs, []string{`[\\/]combined([\\/].*)?$`})
+	assert.NoError(t, err)
+	assert.Equal(t, []assetFile{
+		{Source: `vendor/github.com/nging-plugins/collector/template/a.html`, Name: `template/a.html`},
+		{Source: `public/assets/style.css`, Name: `public/assets/style.css`},
+		{Source: `template/index.html`, Name: `template/index.html`},
+	}, files)
+
+	assert.NoError(t, stageEmbedAssets(root, `!linux`, files))
+	b, err := os.ReadFile(filepath.Join(root, embedAssetsDir, `nonlinux`, `template`, `a.html`))
+	assert.NoError(t, err)
+	assert.Equal(t, `a`, string(b))
+	assert.NoError(t, stageEmbedAssets(root, `!linux`, nil))
+	assert.NoFileExists(t, filepath.Join(root, embedAssetsDir, `nonlinux`, `template`, `a.html`))
+	assert.True(t, isEmbedGoFile(filepath.Join(root, embedGoFileName(`nonlinux`))))
+	assert.False(t, isEmbedGoFile(filepath.Join(root, `main_nonlinux.go`)))
+	assert.Contains(t, embedGoSource(`!linux`, true), "//go:build !linux && bindata\n")
+	assert.Contains(t, embedGoSource(`!linux`, true), "//go:embed all:embed_assets/nonlinux\n")
+}
+
+func TestFindAssetCollisions(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`template/index.html`: `index`,
+		`vendor/github.com/nging-plugins/collector/template/common/header.html`: `a`,
+		`vendor/github.com/nging-plugins/dbmanager/template/common/header.html`: `b`,
+		`vendor/github.com/nging-plugins/dbmanager/template/common/footer.html`: `b`,
+		`vendor/github.com/nging-plugins/collector/template/index.html`:         `c`,
+		`vendor/github.com/nging-plugins/collector/template/index.less`:         `ignored`,
+		`template/index.less`: `ignored`,
+	})
+	prefixes, miscDirs := buildGoGenerateCommandData(context.Background(), []string{
+		`vendor/github.com/nging-plugins/collector/template/`,
+		`vendor/github.com/nging-plugins/dbmanager/template/`,
+		`template/`,
+		`template/`,
+	})
+	files, err := collectAssetFiles(root, prefixes, miscDirs, nil)
+	assert.NoError(t, err)
+	collisions := findAssetCollisions(files, nil)
+	assert.Equal(t, []assetCollision{
+		{Name: `template/common/header.html`, Sources: []string{
+			`vendor/github.com/nging-plugins/collector/template/common/header.html`,
+			`vendor/github.com/nging-plugins/dbmanager/template/common/header.html`,
+		}},
+		{Name: `template/index.html`, Sources: []string{
+			`vendor/github.com/nging-plugins/collector/template/index.html`,
+			`template/index.html`,
+		}},
+	}, collisions)
+	err = assetCollisionError(`linux`, collisions)
+	assert.Contains(t, err.Error(), `found 2 embedded asset path collision(s) for VendorMiscDirs key "linux"`)
+	assert.Contains(t, err.Error(), "\n  template/common/header.html\n    <= vendor/github.com/nging-plugins/collector/template/common/header.html")
+
+	collisions = findAssetCollisions(files, []string{`template/common/*`, `template/index.html`})
+	assert.Empty(t, collisions)
+}
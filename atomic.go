@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// atomicFile 先写入同一目录中的临时文件，完成后重命名为目标文件，避免其它进程读取到未写完的文件
+type atomicFile struct {
+	*os.File
+	name string
+}
+
+func createAtomic(name string) (*atomicFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(name), `.`+filepath.Base(name)+`.tmp-*`)
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: f, name: name}, nil
+}
+
+// commit 关闭临时文件并重命名为目标文件。失败时删除临时文件
+func (f *atomicFile) commit() error {
+	err := f.Sync()
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), f.name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// abort 放弃写入并删除临时文件
+func (f *atomicFile) abort() {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// copyFileAtomic 复制文件。中断时目标文件保持不变，不会留下只写了一部分的文件
+func copyFileAtomic(src string, dst string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	f, err := createAtomic(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.abort()
+		return err
+	}
+	return f.commit()
+}
+
+func writeFileAtomic(name string, data []byte) error {
+	f, err := createAtomic(name)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.abort()
+		return err
+	}
+	return f.commit()
+}
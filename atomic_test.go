@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, `checksums.txt`)
+	assert.NoError(t, writeFileAtomic(file, []byte(`old`)))
+	f, err := createAtomic(file)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`new`)
+	assert.NoError(t, err)
+
+	// 提交之前读取到的仍然是旧文件
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, `old`, string(b))
+	assert.NoError(t, f.commit())
+	b, err = os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, `new`, string(b))
+
+	f, err = createAtomic(file)
+	assert.NoError(t, err)
+	f.WriteString(`partial`)
+	f.abort()
+	b, err = os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, `new`, string(b))
+
+	// 不留下临时文件
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestCopyFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, `bindata_assetfs.go`)
+	dst := filepath.Join(dir, `cache`, `bindata_assetfs.go`)
+	assert.NoError(t, os.WriteFile(src, []byte(`package main`), 0644))
+	assert.Error(t, copyFileAtomic(src, dst))
+	assert.NoError(t, os.Mkdir(filepath.Dir(dst), os.ModePerm))
+	assert.NoError(t, copyFileAtomic(src, dst))
+	b, err := os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, `package main`, string(b))
+	assert.Error(t, copyFileAtomic(filepath.Join(dir, `missing.go`), dst))
+	entries, err := os.ReadDir(filepath.Dir(dst))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
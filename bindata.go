@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// BindataConfig 资源生成工具(go-bindata)配置
+type BindataConfig struct {
+	Module     string   // 生成工具的包路径，默认: github.com/admpub/bindata/v3/go-bindata
+	Version    string   // 生成工具的版本，默认: v3.1.5。使用固定版本以便重新构建旧版本时结果一致，不建议使用 latest
+	Output     string   // 生成的文件名，默认: bindata_assetfs.go
+	Ignore     string   // 默认忽略的文件(正则表达式)
+	Minify     string   // 需要压缩的文件(正则表达式)，为 `-` 时不压缩
+	Tags       string   // 生成文件的构建标签，默认: bindata
+	ExtraFlags []string // 其它参数
+}
+
+// 默认的资源生成工具配置
+var defaultBindataConfig = BindataConfig{
+	Module:  `github.com/admpub/bindata/v3/go-bindata`,
+	Version: `v3.1.5`,
+	Output:  `bindata_assetfs.go`,
+	Ignore:  defaultBindataIgnore,
+	Minify:  `\.(js|css)$`,
+	Tags:    `bindata`,
+}
+
+func (b BindataConfig) Clone() BindataConfig {
+	c := b
+	c.ExtraFlags = make([]string, len(b.ExtraFlags))
+	copy(c.ExtraFlags, b.ExtraFlags)
+	return c
+}
+
+// WithDefaults 为未设置的项填充默认值
+func (b BindataConfig) WithDefaults() BindataConfig {
+	c := b.Clone()
+	if len(c.Module) == 0 {
+		c.Module = defaultBindataConfig.Module
+	}
+	if len(c.Version) == 0 {
+		c.Version = defaultBindataConfig.Version
+	}
+	if len(c.Output) == 0 {
+		c.Output = defaultBindataConfig.Output
+	}
+	if len(c.Ignore) == 0 {
+		c.Ignore = defaultBindataConfig.Ignore
+	}
+	if len(c.Minify) == 0 {
+		c.Minify = defaultBindataConfig.Minify
+	} else if c.Minify == `-` {
+		c.Minify = ``
+	}
+	if len(c.Tags) == 0 {
+		c.Tags = defaultBindataConfig.Tags
+	}
+	return c
+}
+
+var majorVersionRegexp = regexp.MustCompile(`^v[0-9]+$`)
+
+// Command 返回 go install 安装后的命令名称
+func (b BindataConfig) Command() string {
+	name := path.Base(b.Module)
+	if majorVersionRegexp.MatchString(name) {
+		name = path.Base(path.Dir(b.Module))
+	}
+	return name
+}
+
+func quoteGenerateArg(arg string) string {
+	if strings.ContainsAny(arg, " \t\"\\") {
+		return fmt.Sprintf(`%q`, arg)
+	}
+	return arg
+}
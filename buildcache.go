@@ -0,0 +1,451 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webx-top/com"
+)
+
+// 参与构建缓存 key 计算的源文件扩展名
+var buildSourceExtensions = []string{`.go`, `.s`, `.c`, `.h`, `.cc`, `.cpp`, `.hpp`, `.m`, `.syso`}
+
+// 参与构建缓存 key 计算的其它文件
+var buildSourceFiles = []string{`go.mod`, `go.sum`, `go.work`, `go.work.sum`, `modules.txt`}
+
+// buildCache 以构建参数及源文件、资源文件内容为 key 缓存编译生成的可执行文件
+type buildCache struct {
+	dir       string         // 缓存目录，为空时不使用缓存
+	gen       *generateCache // 用于计算资源文件的 key
+	remote    *remoteCache   // 远程缓存，为 nil 时不使用
+	skipDirs  []string       // 计算源文件 key 时跳过的目录(绝对路径)
+	sources   map[string]string
+	modules   map[string][]string
+	toolchain *string
+}
+
+func newBuildCache(dir string, gen *generateCache, skipDirs ...string) *buildCache {
+	return &buildCache{
+		dir:      dir,
+		gen:      gen,
+		skipDirs: skipDirs,
+		sources:  map[string]string{},
+		modules:  map[string][]string{},
+	}
+}
+
+// sourcesHash 计算目录中所有 Go 源文件(包括 vendor 目录，不包括测试文件及生成的资源文件)及其 //go:embed 引用的文件的哈希值
+func (b *buildCache) sourcesHash(root string, excludes ...string) (string, error) {
+	if sum, ok := b.sources[root]; ok {
+		return sum, nil
+	}
+	h := sha256.New()
+	var embeds []string
+	err := filepath.WalkDir(root, func(fpath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if fpath != root && (strings.HasPrefix(name, `.`) || strings.HasPrefix(name, `_`) || name == `testdata` || name == `node_modules` || name == embedAssetsDir || slices.Contains(b.skipDirs, fpath)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(name, `_test.go`) || slices.Contains(excludes, fpath) {
+			return nil
+		}
+		if !slices.Contains(buildSourceExtensions, filepath.Ext(name)) && !slices.Contains(buildSourceFiles, name) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, fpath)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "file=%s\n", filepath.ToSlash(rel))
+		if err = hashFile(h, fpath); err != nil {
+			return err
+		}
+		if filepath.Ext(name) != `.go` {
+			return nil
+		}
+		files, err := embedFiles(fpath)
+		embeds = append(embeds, files...)
+		return err
+	})
+	if err != nil {
+		return ``, err
+	}
+	slices.Sort(embeds)
+	for _, fpath := range slices.Compact(embeds) {
+		rel, err := filepath.Rel(root, fpath)
+		if err != nil {
+			return ``, err
+		}
+		// 生成的资源目录由资源文件的 key 计算
+		if slices.Contains(strings.Split(filepath.ToSlash(rel), `/`), embedAssetsDir) {
+			continue
+		}
+		fmt.Fprintf(h, "embed=%s\n", filepath.ToSlash(rel))
+		if err = hashFile(h, fpath); err != nil {
+			return ``, err
+		}
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	b.sources[root] = sum
+	return sum, nil
+}
+
+// embedFiles 返回 Go 源文件中 //go:embed 指令引用的文件(目录中的所有文件)
+func embedFiles(goFile string) ([]string, error) {
+	b, err := os.ReadFile(goFile)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(goFile)
+	var files []string
+	for _, line := range strings.Split(string(b), "\n") {
+		args, ok := strings.CutPrefix(strings.TrimSpace(line), `//go:embed`)
+		if !ok {
+			continue
+		}
+		for _, pattern := range parseEmbedPatterns(args) {
+			pattern = strings.TrimPrefix(pattern, `all:`)
+			matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				err = filepath.WalkDir(match, func(fpath string, d fs.DirEntry, err error) error {
+					if err == nil && !d.IsDir() {
+						files = append(files, fpath)
+					}
+					return err
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+// parseEmbedPatterns 解析 //go:embed 指令的参数，参数以空白分隔，可以使用 Go 字符串字面量
+func parseEmbedPatterns(args string) []string {
+	var patterns []string
+	for args = strings.TrimSpace(args); len(args) > 0; args = strings.TrimSpace(args) {
+		if args[0] == '"' || args[0] == '`' {
+			end := strings.IndexByte(args[1:], args[0])
+			if end < 0 {
+				break
+			}
+			if pattern, err := strconv.Unquote(args[:end+2]); err == nil {
+				patterns = append(patterns, pattern)
+			}
+			args = args[end+2:]
+			continue
+		}
+		end := strings.IndexAny(args, " \t")
+		if end < 0 {
+			end = len(args)
+		}
+		patterns = append(patterns, args[:end])
+		args = args[end:]
+	}
+	return patterns
+}
+
+// targetFiles 单文件模式下发布目录为所有目标共用，只保留当前目标生成的 <executor>-<goos>-<goarch>* 文件
+func targetFiles(files []string, prefix string) []string {
+	return slices.DeleteFunc(files, func(name string) bool {
+		return !strings.HasPrefix(name, prefix)
+	})
+}
+
+type modReplace struct {
+	New struct {
+		Path    string
+		Version string
+	}
+}
+
+// localModuleDirs 返回 go.mod 中 replace 到本地目录的模块以及 go.work 中的模块所在目录(不包括项目目录中的模块)。
+// 这些模块不在 GOMODCACHE 中，内容可能随时被修改，需要参与构建缓存 key 的计算
+func (b *buildCache) localModuleDirs(ctx context.Context, root string) []string {
+	if dirs, ok := b.modules[root]; ok {
+		return dirs
+	}
+	var dirs []string
+	add := func(baseDir string, dir string) {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(baseDir, dir)
+		}
+		dir = filepath.Clean(dir)
+		if rel, err := filepath.Rel(root, dir); err == nil && rel != `..` && !strings.HasPrefix(rel, `..`+string(filepath.Separator)) {
+			return
+		}
+		if com.IsDir(dir) && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	var mod struct {
+		Replace []modReplace
+	}
+	if goJSON(ctx, root, &mod, `mod`, `edit`, `-json`) == nil {
+		for _, r := range mod.Replace {
+			if len(r.New.Version) == 0 {
+				add(root, r.New.Path)
+			}
+		}
+	}
+	cmd := exec.CommandContext(ctx, `go`, `env`, `GOWORK`)
+	cmd.Dir = root
+	out, _ := cmd.Output()
+	if workFile := strings.TrimSpace(string(out)); len(workFile) > 0 && workFile != `off` {
+		var work struct {
+			Use []struct {
+				DiskPath string
+			}
+			Replace []modReplace
+		}
+		if goJSON(ctx, root, &work, `work`, `edit`, `-json`, workFile) == nil {
+			workDir := filepath.Dir(workFile)
+			for _, u := range work.Use {
+				add(workDir, u.DiskPath)
+			}
+			for _, r := range work.Replace {
+				if len(r.New.Version) == 0 {
+					add(workDir, r.New.Path)
+				}
+			}
+		}
+	}
+	slices.Sort(dirs)
+	b.modules[root] = dirs
+	return dirs
+}
+
+// goJSON 执行 go 命令并解析输出的 JSON
+func goJSON(ctx context.Context, dir string, v any, args ...string) error {
+	cmd := exec.CommandContext(ctx, `go`, args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(out, v)
+}
+
+// goVersion 返回本地 Go 工具链的版本
+func (b *buildCache) goVersion(ctx context.Context, p buildParam) string {
+	if b.toolchain != nil {
+		return *b.toolchain
+	}
+	cmd := exec.CommandContext(ctx, `go`, `env`, `GOVERSION`)
+	cmd.Dir = p.ProjectPath
+	out, _ := cmd.Output()
+	v := strings.TrimSpace(string(out))
+	b.toolchain = &v
+	return v
+}
+
+// key 计算目标的构建缓存 key。ldflags 中不包括构建时间
+func (b *buildCache) key(ctx context.Context, p buildParam) (string, error) {
+	if len(b.dir) == 0 {
+		return ``, nil
+	}
+	h := sha256.New()
+	p.NgingBuildTime = ``
+	tags := p.buildTags()
+	fmt.Fprintf(h, "target=%s\ngoos=%s\ngoarch=%s\n", p.Target, p.goos, p.goarch)
+	fmt.Fprintf(h, "compiler=%s\ncgo=%v\ngoVersion=%s\ngoImage=%s\n", p.Compiler, p.CgoEnabled, p.GoVersion, p.GoImage)
+	if p.Compiler == compilerGo {
+		fmt.Fprintf(h, "toolchain=%s\n", b.goVersion(ctx, p))
+	}
+	fmt.Fprintf(h, "tags=%s\nldflags=%s\n", strings.Join(tags, ` `), p.genLdFlagsString())
+	fmt.Fprintf(h, "executor=%s\nextension=%s\nproject=%s\n", p.Executor, p.Extension, p.Project)
+	excludes := []string{filepath.Join(p.ProjectPath, p.Bindata.WithDefaults().Output)}
+	sum, err := b.sourcesHash(p.ProjectPath, excludes...)
+	if err != nil {
+		return ``, err
+	}
+	fmt.Fprintf(h, "sources=%s\n", sum)
+	for _, dir := range b.localModuleDirs(ctx, p.ProjectPath) {
+		if sum, err = b.sourcesHash(dir); err != nil {
+			return ``, err
+		}
+		rel, err := filepath.Rel(p.ProjectPath, dir)
+		if err != nil {
+			return ``, err
+		}
+		fmt.Fprintf(h, "module=%s\nmoduleSources=%s\n", filepath.ToSlash(rel), sum)
+	}
+	if len(p.StartupPackage) > 0 {
+		startupDir, err := filepath.Abs(strings.SplitN(p.StartupPackage, `@`, 2)[0])
+		if err != nil {
+			return ``, err
+		}
+		sum, err = b.sourcesHash(startupDir)
+		if err != nil {
+			return ``, err
+		}
+		fmt.Fprintf(h, "startup=%s\nstartupSources=%s\n", p.StartupPackage, sum)
+	}
+	assetsKey, err := b.gen.key(ctx, p)
+	if err != nil {
+		return ``, err
+	}
+	fmt.Fprintf(h, "assets=%s\n", assetsKey)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshotReleaseDir 记录发布目录中的文件，用于找出编译生成的文件
+func snapshotReleaseDir(dir string) map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		stamps[entry.Name()] = fileStamp{size: fi.Size(), modTime: fi.ModTime()}
+	}
+	return stamps
+}
+
+// changedFiles 返回与快照相比新增或修改的文件
+func changedFiles(dir string, before map[string]fileStamp) []string {
+	var files []string
+	for name, stamp := range snapshotReleaseDir(dir) {
+		if old, ok := before[name]; ok && old == stamp {
+			continue
+		}
+		files = append(files, name)
+	}
+	slices.Sort(files)
+	return files
+}
+
+// restore 缓存命中时将可执行文件复制到发布目录。本地缓存不存在时尝试从远程缓存下载。
+// 读取缓存出错时只输出警告并重新编译，只有被中断时才返回错误
+func (b *buildCache) restore(ctx context.Context, key string, releaseDir string) (bool, error) {
+	if len(b.dir) == 0 || len(key) == 0 {
+		return false, nil
+	}
+	ok, err := b.copyTo(ctx, key, releaseDir)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, err
+		}
+		fmt.Println(`Warning		:	 failed to restore cached binary:`, err)
+		return false, nil
+	}
+	return ok, nil
+}
+
+func (b *buildCache) copyTo(ctx context.Context, key string, releaseDir string) (bool, error) {
+	cacheDir := filepath.Join(b.dir, `build`, key)
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if len(entries) == 0 {
+		ok, err := b.fetchRemote(ctx, key)
+		if err != nil || !ok {
+			return false, err
+		}
+		if entries, err = os.ReadDir(cacheDir); err != nil {
+			return false, err
+		}
+	}
+	if err = com.MkdirAll(releaseDir, os.ModePerm); err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if err = com.Copy(filepath.Join(cacheDir, entry.Name()), filepath.Join(releaseDir, entry.Name())); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// fetchRemote 从远程缓存下载到本地缓存目录
+func (b *buildCache) fetchRemote(ctx context.Context, key string) (bool, error) {
+	if b.remote == nil {
+		return false, nil
+	}
+	tmpDir, err := b.tempDir()
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(tmpDir)
+	ok, err := b.remote.fetch(ctx, key, tmpDir)
+	if err != nil || !ok {
+		return false, err
+	}
+	fmt.Println(`Build		:	 downloaded from remote cache`, key[:12])
+	return true, b.commit(tmpDir, key)
+}
+
+func (b *buildCache) tempDir() (string, error) {
+	buildDir := filepath.Join(b.dir, `build`)
+	if err := com.MkdirAll(buildDir, os.ModePerm); err != nil {
+		return ``, err
+	}
+	return os.MkdirTemp(buildDir, `.tmp-`)
+}
+
+// commit 将临时目录重命名为缓存目录。缓存内容由 key 决定，缓存目录已存在(其它进程已写入)时不再替换，
+// 避免正在读取该缓存的进程失败
+func (b *buildCache) commit(tmpDir string, key string) error {
+	cacheDir := filepath.Join(b.dir, `build`, key)
+	err := os.Rename(tmpDir, cacheDir)
+	if err != nil && com.IsDir(cacheDir) {
+		return nil
+	}
+	return err
+}
+
+// store 保存编译生成的文件，并上传到远程缓存
+func (b *buildCache) store(ctx context.Context, key string, releaseDir string, files []string) error {
+	if len(b.dir) == 0 || len(key) == 0 || len(files) == 0 {
+		return nil
+	}
+	tmpDir, err := b.tempDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	for _, name := range files {
+		if err = com.Copy(filepath.Join(releaseDir, name), filepath.Join(tmpDir, name)); err != nil {
+			return err
+		}
+	}
+	if err = b.commit(tmpDir, key); err != nil {
+		return err
+	}
+	if b.remote == nil {
+		return nil
+	}
+	return b.remote.upload(ctx, key, filepath.Join(b.dir, `build`, key), files)
+}
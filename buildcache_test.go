@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildCacheKey(t *testing.T) {
+	original := p.Clone()
+	defer func() {
+		p = original
+	}()
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`go.mod`:                  "module example.com/proj\n",
+		`main.go`:                 "package main\n",
+		`main_test.go`:            "package main\n",
+		`bindata_assetfs.go`:      "package main\n",
+		`dist/nging`:              `binary`,
+		`template/index.html`:     `index`,
+		`vendor/modules.txt`:      "# example.com/dep v1.0.0\n",
+		`vendor/example.com/a.go`: "package a\n",
+	})
+	p.ProjectPath = root
+	p.AssetDirs = nil
+	p.AutoDiscoveryMiscDir = false
+	p.AssetGenerator = ``
+	p.Bindata = BindataConfig{}
+	p.Compiler = compilerXgo
+	p.goos, p.goarch = `linux`, `amd64`
+	vendorMiscDirs := map[string][]string{`*`: {}, `linux`: {}}
+	newCache := func() *buildCache {
+		return newBuildCache(t.TempDir(), newGenerateCache(``, vendorMiscDirs), filepath.Join(root, `dist`))
+	}
+	ctx := context.Background()
+	key, err := newCache().key(ctx, p)
+	assert.NoError(t, err)
+	assert.Len(t, key, 64)
+
+	changed := func(fn func(), expected bool) {
+		fn()
+		k, err := newCache().key(ctx, p)
+		assert.NoError(t, err)
+		if expected {
+			assert.NotEqual(t, key, k)
+		} else {
+			assert.Equal(t, key, k)
+		}
+		key = k
+	}
+	changed(func() { p.NgingBuildTime = `20250101000000` }, false)
+	changed(func() { p.CopyFiles = []string{`config/ua.txt`} }, false)
+	changed(func() { writeTestFiles(t, root, map[string]string{`main_test.go`: "package main\n\n"}) }, false)
+	changed(func() { writeTestFiles(t, root, map[string]string{`bindata_assetfs.go`: "package main\n\n"}) }, false)
+	changed(func() { writeTestFiles(t, root, map[string]string{`dist/a.go`: "package dist\n"}) }, false)
+	changed(func() { p.BuildTags = append(p.BuildTags, `db_mysql`) }, true)
+	changed(func() { p.LdFlags = append(p.LdFlags, `-s`) }, true)
+	changed(func() { p.GoVersion = `1.99.0` }, true)
+	changed(func() { writeTestFiles(t, root, map[string]string{`main.go`: "package main\n\n"}) }, true)
+	changed(func() { writeTestFiles(t, root, map[string]string{`vendor/example.com/a.go`: "package a\n\n"}) }, true)
+	changed(func() { writeTestFiles(t, root, map[string]string{`template/index.html`: `changed`}) }, true)
+	// //go:embed 引用的非 Go 文件
+	changed(func() { writeTestFiles(t, root, map[string]string{`public/a.txt`: `a`, `public/b.dat`: `b`}) }, false)
+	changed(func() {
+		writeTestFiles(t, root, map[string]string{`static.go`: "package main\n\nimport _ \"embed\"\n\n//go:embed public/*.txt \"embed_assets\"\nvar a string\n"})
+	}, true)
+	changed(func() { writeTestFiles(t, root, map[string]string{`public/a.txt`: `changed`}) }, true)
+	changed(func() { writeTestFiles(t, root, map[string]string{`public/b.dat`: `changed`}) }, false)
+	changed(func() { writeTestFiles(t, root, map[string]string{`embed_assets/linux/a.html`: `changed`}) }, false)
+
+	key, err = newBuildCache(``, newGenerateCache(``, vendorMiscDirs)).key(ctx, p)
+	assert.NoError(t, err)
+	assert.Empty(t, key)
+}
+
+func TestBuildCacheStoreRestore(t *testing.T) {
+	releaseDir := t.TempDir()
+	writeTestFiles(t, releaseDir, map[string]string{`nging`: `old`})
+	cache := newBuildCache(t.TempDir(), nil)
+	before := snapshotReleaseDir(releaseDir)
+	writeTestFiles(t, releaseDir, map[string]string{
+		`nging-linux-amd64`: `binary`,
+		`startup`:           `startup`,
+	})
+	assert.NoError(t, os.Chmod(filepath.Join(releaseDir, `nging-linux-amd64`), 0755))
+	files := changedFiles(releaseDir, before)
+	assert.Equal(t, []string{`nging-linux-amd64`, `startup`}, files)
+	assert.NoError(t, cache.store(context.Background(), `abc`, releaseDir, files))
+
+	ok, err := cache.restore(context.Background(), `def`, releaseDir)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	restoreDir := filepath.Join(t.TempDir(), `nging_linux_amd64`)
+	ok, err = cache.restore(context.Background(), `abc`, restoreDir)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	b, err := os.ReadFile(filepath.Join(restoreDir, `nging-linux-amd64`))
+	assert.NoError(t, err)
+	assert.Equal(t, `binary`, string(b))
+	fi, err := os.Stat(filepath.Join(restoreDir, `nging-linux-amd64`))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+	assert.NoFileExists(t, filepath.Join(restoreDir, `nging`))
+}
+
+func TestParseEmbedPatterns(t *testing.T) {
+	assert.Equal(t, []string{`a.txt`, `all:public`, `b c.txt`, `d.txt`}, parseEmbedPatterns(" a.txt\tall:public \"b c.txt\" `d.txt`"))
+	assert.Empty(t, parseEmbedPatterns(``))
+}
+
+func TestTargetFiles(t *testing.T) {
+	files := []string{`nging-darwin-arm64`, `nging-linux-amd64`, `nging-linux-amd64.exe`, `startup`}
+	assert.Equal(t, []string{`nging-linux-amd64`, `nging-linux-amd64.exe`}, targetFiles(files, `nging-linux-amd64`))
+}
+
+func TestBuildCacheLocalModules(t *testing.T) {
+	original := p.Clone()
+	defer func() {
+		p = original
+	}()
+	parent := t.TempDir()
+	root := filepath.Join(parent, `proj`)
+	writeTestFiles(t, parent, map[string]string{
+		`proj/go.mod`:   "module example.com/proj\n\ngo 1.21\n\nrequire example.com/plugin v1.0.0\n\nreplace example.com/plugin => ../plugin\n\nreplace example.com/missing => ../missing\n",
+		`proj/main.go`:  "package main\n",
+		`plugin/go.mod`: "module example.com/plugin\n",
+		`plugin/a.go`:   "package plugin\n",
+		`plugin/a.html`: `a`,
+		`unused/b.go`:   "package unused\n",
+	})
+	p.ProjectPath = root
+	p.AssetDirs = nil
+	p.AutoDiscoveryMiscDir = false
+	p.Bindata = BindataConfig{}
+	vendorMiscDirs := map[string][]string{`*`: {}}
+	ctx := context.Background()
+	newCache := func() *buildCache {
+		return newBuildCache(t.TempDir(), newGenerateCache(``, vendorMiscDirs))
+	}
+	assert.Equal(t, []string{filepath.Join(parent, `plugin`)}, newCache().localModuleDirs(ctx, root))
+	key, err := newCache().key(ctx, p)
+	assert.NoError(t, err)
+
+	// 修改 replace 到本地目录的模块时缓存失效
+	writeTestFiles(t, parent, map[string]string{`plugin/a.go`: "package plugin\n\n"})
+	k, err := newCache().key(ctx, p)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, k)
+	writeTestFiles(t, parent, map[string]string{`unused/b.go`: "package unused\n\n"})
+	key, err = newCache().key(ctx, p)
+	assert.NoError(t, err)
+	assert.Equal(t, k, key)
+}
+
+func TestBuildCacheConcurrentCommit(t *testing.T) {
+	releaseDir := t.TempDir()
+	writeTestFiles(t, releaseDir, map[string]string{`nging-linux-amd64`: `binary`})
+	cache := newBuildCache(t.TempDir(), nil)
+	ctx := context.Background()
+	assert.NoError(t, cache.store(ctx, `abc`, releaseDir, []string{`nging-linux-amd64`}))
+	// 其它进程已写入相同 key 的缓存
+	assert.NoError(t, cache.store(ctx, `abc`, releaseDir, []string{`nging-linux-amd64`}))
+	entries, err := os.ReadDir(filepath.Join(cache.dir, `build`))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// 读取缓存出错时当作未命中
+	target := filepath.Join(t.TempDir(), `file`)
+	writeTestFiles(t, filepath.Dir(target), map[string]string{`file`: ``})
+	ok, err := cache.restore(ctx, `abc`, filepath.Join(target, `nging_linux_amd64`))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/webx-top/com"
+)
+
+// StageTimeouts 各构建阶段的超时时间(秒)，每个目标单独计时。为 0 时不限制
+type StageTimeouts struct {
+	Generate int // 生成资源文件(go generate)
+	Build    int // 编译(包括 startup)
+	Pack     int // 打包
+	Hook     int // 每个钩子命令
+}
+
+// stageContext 返回带有阶段超时时间的 context
+func stageContext(ctx context.Context, stage string, seconds int) (context.Context, context.CancelFunc) {
+	if seconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	timeout := time.Duration(seconds) * time.Second
+	return context.WithTimeoutCause(ctx, timeout, fmt.Errorf(`%s timed out after %v`, stage, timeout))
+}
+
+// runStage 在阶段超时时间内执行 fn，超时或被中断时删除当前目标未完成的输出并退出
+func runStage(ctx context.Context, stage string, seconds int, fn func(context.Context)) {
+	stageCtx, cancel := stageContext(ctx, stage, seconds)
+	defer cancel()
+	fn(stageCtx)
+	exitIfCanceled(stageCtx)
+}
+
+// signalContext 收到 SIGINT/SIGTERM 时取消 context。再次收到信号时直接退出
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// partialOutputs 当前目标尚未完成的输出，构建被中断或超时时删除
+type partialOutputs struct {
+	releaseDir string
+	shared     bool // 发布目录为所有目标共用(单文件模式)，只删除当前目标生成的文件
+	before     map[string]fileStamp
+	archive    string
+}
+
+var partial partialOutputs
+
+// begin 开始构建目标
+func (o *partialOutputs) begin(releaseDir string, shared bool) {
+	*o = partialOutputs{releaseDir: releaseDir, shared: shared}
+	if shared {
+		o.before = snapshotReleaseDir(releaseDir)
+	}
+}
+
+// done 目标的所有输出都已完成
+func (o *partialOutputs) done() {
+	*o = partialOutputs{}
+}
+
+func (o *partialOutputs) cleanup() {
+	if len(o.releaseDir) > 0 {
+		if o.shared {
+			for _, name := range changedFiles(o.releaseDir, o.before) {
+				os.Remove(filepath.Join(o.releaseDir, name))
+			}
+		} else {
+			os.RemoveAll(o.releaseDir)
+		}
+	}
+	if len(o.archive) > 0 {
+		os.Remove(o.archive)
+	}
+	o.done()
+}
+
+// exitIfCanceled 构建被中断或超时时删除当前目标未完成的输出并退出
+func exitIfCanceled(ctx context.Context) {
+	if ctx.Err() == nil {
+		return
+	}
+	partial.cleanup()
+	com.ExitOnFailure(fmt.Sprintf("Error		:	 %v, partial outputs removed\n", context.Cause(ctx)), 1)
+}
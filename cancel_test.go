@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartialOutputs(t *testing.T) {
+	distPath := t.TempDir()
+	archive := filepath.Join(distPath, `packed`, `nging_linux_amd64.tar.gz`)
+	releaseDir := filepath.Join(distPath, `nging_linux_amd64`)
+	writeTestFiles(t, distPath, map[string]string{
+		`nging_linux_amd64/nging`:          `half`,
+		`packed/nging_linux_amd64.tar.gz`:  `half`,
+		`packed/nging_darwin_arm64.tar.gz`: `done`,
+	})
+	partial.begin(releaseDir, false)
+	partial.archive = archive
+	partial.cleanup()
+	assert.NoDirExists(t, releaseDir)
+	assert.NoFileExists(t, archive)
+	assert.FileExists(t, filepath.Join(distPath, `packed`, `nging_darwin_arm64.tar.gz`))
+
+	// 单文件模式只删除当前目标生成的文件
+	writeTestFiles(t, distPath, map[string]string{`nging-darwin-arm64`: `done`})
+	partial.begin(distPath, true)
+	writeTestFiles(t, distPath, map[string]string{`nging-linux-amd64`: `half`})
+	partial.cleanup()
+	assert.NoFileExists(t, filepath.Join(distPath, `nging-linux-amd64`))
+	assert.FileExists(t, filepath.Join(distPath, `nging-darwin-arm64`))
+
+	// 已完成的目标不再删除
+	partial.begin(releaseDir, false)
+	partial.done()
+	writeTestFiles(t, releaseDir, map[string]string{`nging`: `done`})
+	partial.cleanup()
+	assert.FileExists(t, filepath.Join(releaseDir, `nging`))
+}
+
+func TestStageContext(t *testing.T) {
+	ctx, cancel := stageContext(context.Background(), `build`, 0)
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	cancel()
+
+	ctx, cancel = stageContext(context.Background(), `build`, 1)
+	defer cancel()
+	<-ctx.Done()
+	assert.EqualError(t, context.Cause(ctx), `build timed out after 1s`)
+}
+
+func TestHookTimeout(t *testing.T) {
+	bp := buildParam{}
+	bp.ProjectPath = t.TempDir()
+	bp.Timeouts.Hook = 1
+	bp.Hooks.AfterBuild = []Hook{{Command: `sleep 10`, AllowFailure: true}}
+	start := time.Now()
+	bp.runHooks(context.Background(), hookAfterBuild, ``)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
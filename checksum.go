@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const defaultChecksumAlgorithm = `sha256`
+
+// 支持的校验和算法
+var checksumHashes = map[string]func() hash.Hash{
+	`md5`:    md5.New,
+	`sha1`:   sha1.New,
+	`sha256`: sha256.New,
+	`sha512`: sha512.New,
+}
+
+// checksumAlgorithms 返回要生成的校验和算法。sha256 始终排在第一位
+func checksumAlgorithms(extra []string) ([]string, error) {
+	algos := []string{defaultChecksumAlgorithm}
+	for _, algo := range extra {
+		algo = strings.ToLower(strings.TrimSpace(algo))
+		if len(algo) == 0 || slices.Contains(algos, algo) {
+			continue
+		}
+		if _, ok := checksumHashes[algo]; !ok {
+			return nil, fmt.Errorf(`unsupported checksum algorithm: %s`, algo)
+		}
+		algos = append(algos, algo)
+	}
+	return algos, nil
+}
+
+// fileChecksum 文件的校验和。key: 算法; value: 十六进制校验和
+type fileChecksum struct {
+	File string
+	Size int64
+	Sums map[string]string
+}
+
+// checksumWriter 将写入的数据同时交给所有哈希算法计算
+type checksumWriter struct {
+	w      io.Writer
+	algos  []string
+	hashes []hash.Hash
+	size   int64
+}
+
+func newChecksumWriter(w io.Writer, algos []string) *checksumWriter {
+	c := &checksumWriter{w: w, algos: algos, hashes: make([]hash.Hash, len(algos))}
+	for i, algo := range algos {
+		c.hashes[i] = checksumHashes[algo]()
+	}
+	return c
+}
+
+func (c *checksumWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	for _, h := range c.hashes {
+		h.Write(b[:n])
+	}
+	c.size += int64(n)
+	return n, err
+}
+
+func (c *checksumWriter) checksum(file string) fileChecksum {
+	r := fileChecksum{File: file, Size: c.size, Sums: make(map[string]string, len(c.algos))}
+	for i, algo := range c.algos {
+		r.Sums[algo] = hex.EncodeToString(c.hashes[i].Sum(nil))
+	}
+	return r
+}
+
+// checksumFile 读取一次文件计算所有算法的校验和
+func checksumFile(file string, algos []string) (fileChecksum, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return fileChecksum{}, err
+	}
+	defer f.Close()
+	w := newChecksumWriter(io.Discard, algos)
+	if _, err = io.CopyBuffer(w, f, make([]byte, 1024*1024)); err != nil {
+		return fileChecksum{}, err
+	}
+	return w.checksum(file), nil
+}
+
+// checksumSuffix 单个文件校验和的文件扩展名，例如: .sha256
+func checksumSuffix(algo string) string {
+	return `.` + algo
+}
+
+// checksumsFileName 合并的校验和文件名。sha256 为 checksums.txt，其它算法为 checksums.<算法>.txt
+func checksumsFileName(algo string) string {
+	if algo == defaultChecksumAlgorithm {
+		return `checksums.txt`
+	}
+	return `checksums.` + algo + `.txt`
+}
+
+// writeChecksumFiles 在文件旁生成 <文件名>.<算法> 校验和文件
+func writeChecksumFiles(sum fileChecksum, algos []string) error {
+	for _, algo := range algos {
+		err := writeFileAtomic(sum.File+checksumSuffix(algo), []byte(sum.Sums[algo]+` `+filepath.Base(sum.File)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeChecksums 合并生成 checksums.txt，保留其中不在本次构建中的文件的校验和
+func makeChecksums(sums []fileChecksum, saveDir string, algos []string) error {
+	if len(saveDir) > 0 {
+		os.MkdirAll(saveDir, os.ModePerm)
+	}
+	for _, algo := range algos {
+		saveFile := filepath.Join(saveDir, checksumsFileName(algo))
+		checksums := map[string]string{}
+		b, err := os.ReadFile(saveFile)
+		if err == nil {
+			lines := strings.Split(string(b), "\n")
+			for _, line := range lines {
+				parts := spaceRegexp.Split(line, 2)
+				if len(parts) == 2 {
+					checksums[parts[1]] = parts[0]
+				}
+			}
+		}
+		lines := make([]string, len(sums))
+		for index, sum := range sums {
+			fileName := filepath.Base(sum.File)
+			lines[index] = sum.Sums[algo] + ` ` + fileName
+			delete(checksums, fileName)
+		}
+		for fileName, result := range checksums {
+			lines = append(lines, result+` `+fileName)
+		}
+		if err = writeFileAtomic(saveFile, []byte(strings.Join(lines, "\n"))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
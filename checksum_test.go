@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksumAlgorithms(t *testing.T) {
+	algos, err := checksumAlgorithms(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`sha256`}, algos)
+	algos, err = checksumAlgorithms([]string{`SHA512`, `sha256`, ` md5 `, `sha512`})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`sha256`, `sha512`, `md5`}, algos)
+	_, err = checksumAlgorithms([]string{`crc32`})
+	assert.EqualError(t, err, `unsupported checksum algorithm: crc32`)
+}
+
+func TestMakeChecksums(t *testing.T) {
+	dir := t.TempDir()
+	algos := []string{`sha256`, `md5`}
+	writeTestFiles(t, dir, map[string]string{`a.tar.gz`: `a`, `b.tar.gz`: `b`})
+	sum, err := checksumFile(filepath.Join(dir, `a.tar.gz`), algos)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), sum.Size)
+	assert.Equal(t, `ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb`, sum.Sums[`sha256`])
+	assert.Equal(t, `0cc175b9c0f1b6a831c399e269772661`, sum.Sums[`md5`])
+
+	assert.NoError(t, writeChecksumFiles(sum, algos))
+	b, err := os.ReadFile(filepath.Join(dir, `a.tar.gz.sha256`))
+	assert.NoError(t, err)
+	assert.Equal(t, sum.Sums[`sha256`]+` a.tar.gz`, string(b))
+	assert.FileExists(t, filepath.Join(dir, `a.tar.gz.md5`))
+
+	// 保留不在本次构建中的文件
+	writeTestFiles(t, dir, map[string]string{`checksums.txt`: "old a.tar.gz\nkept c.tar.gz"})
+	assert.NoError(t, makeChecksums([]fileChecksum{sum}, dir, algos))
+	b, err = os.ReadFile(filepath.Join(dir, `checksums.txt`))
+	assert.NoError(t, err)
+	assert.Equal(t, sum.Sums[`sha256`]+" a.tar.gz\nkept c.tar.gz", string(b))
+	b, err = os.ReadFile(filepath.Join(dir, `checksums.md5.txt`))
+	assert.NoError(t, err)
+	assert.Equal(t, sum.Sums[`md5`]+` a.tar.gz`, string(b))
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// 命令被取消后等待其进程组退出的时间，超时后强制结束
+const commandKillDelay = 5 * time.Second
+
+// newCommand 在独立的进程组中执行命令。ctx 被取消(中断或超时)时结束整个进程组，
+// 而不只是直接启动的进程(例如 xgo 启动的 docker 客户端、钩子中 shell 启动的子进程)。
+// 命令不在终端的前台进程组中，按下 Ctrl-C 时由 builder 负责结束，因此不要将终端作为命令的标准输入
+func newCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = 2 * commandKillDelay
+	return cmd
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/webx-top/com"
+)
+
+const (
+	compilerGo   = `go`
+	compilerXgo  = `xgo`
+	compilerAuto = `auto` // 本机目标使用 go 编译，交叉编译使用 xgo
+)
+
+// CompilerRule 使用某个编译器时对构建参数的调整
+type CompilerRule struct {
+	Tags       map[string]string // 构建标签替换。key: 原标签; value: 新标签(为空时删除原标签)。例如: sqlitecgo -> sqlite
+	CgoEnabled *bool             // 为空时不修改
+}
+
+func (r CompilerRule) Clone() CompilerRule {
+	c := CompilerRule{
+		Tags: map[string]string{},
+	}
+	for k, v := range r.Tags {
+		c.Tags[k] = v
+	}
+	if r.CgoEnabled != nil {
+		cgoEnabled := *r.CgoEnabled
+		c.CgoEnabled = &cgoEnabled
+	}
+	return c
+}
+
+// 默认的编译器链: 优先使用 xgo，不支持时回退到 go
+var defaultCompilerChains = map[string][]string{
+	`*`: {compilerXgo, compilerGo},
+}
+
+// 默认的编译器规则: go 编译时不支持 cgo 版 sqlite，改用纯 go 版 sqlite
+var defaultCompilerRules = map[string]CompilerRule{
+	compilerGo: {Tags: map[string]string{`sqlitecgo`: ``}},
+}
+
+func isHostTarget(osName string, archName string) bool {
+	return osName == runtime.GOOS && strings.SplitN(archName, `-`, 2)[0] == runtime.GOARCH
+}
+
+// compilerSupports 编译器是否支持目标平台(以目标注册表为准)
+func compilerSupports(compiler string, osName string, archName string) bool {
+	switch compiler {
+	case compilerGo:
+		return true
+	case compilerAuto:
+		return compilerSupports(resolveAutoCompiler(osName, archName), osName, archName)
+	}
+	// 其它编译器均按 xgo 处理
+	if t, ok := lookupTargetInfo(osName + `/` + archName); ok {
+		return slices.Contains(t.Compilers, compilerXgo)
+	}
+	return com.InSlice(osName, xgoSupportedPlatforms) && com.InSlice(archName, xgoSupportedAchitectures)
+}
+
+func resolveAutoCompiler(osName string, archName string) string {
+	if isHostTarget(osName, archName) {
+		return compilerGo
+	}
+	return compilerXgo
+}
+
+// matchTarget 目标(例如 linux/amd64)是否与模式匹配。单独的 `*` 匹配所有目标
+func matchTarget(pattern string, target string) bool {
+	if pattern == `*` {
+		return true
+	}
+	ok, _ := path.Match(pattern, target)
+	return ok
+}
+
+// matchCompilerChain 查找与目标匹配的编译器链。有多个模式匹配时，采用非通配字符最多的那一个
+func matchCompilerChain(chains map[string][]string, target string) []string {
+	var matched []string
+	var matchedPattern string
+	score := -1
+	for pattern, chain := range chains {
+		if !matchTarget(pattern, target) {
+			continue
+		}
+		s := len(pattern) - strings.Count(pattern, `*`) - strings.Count(pattern, `?`)
+		if s > score || (s == score && pattern < matchedPattern) {
+			score = s
+			matched = chain
+			matchedPattern = pattern
+		}
+	}
+	return matched
+}
+
+// compilerChain 生成目标的编译器链: 指定的编译器排在最前面，其后是配置中匹配的编译器链
+func compilerChain(chains map[string][]string, requested string, target string) []string {
+	var chain []string
+	if len(requested) > 0 {
+		chain = append(chain, requested)
+	}
+	chain = append(chain, matchCompilerChain(chains, target)...)
+	if len(chain) == 0 {
+		chain = append(chain, compilerXgo)
+	}
+	unique := make([]string, 0, len(chain))
+	for _, compiler := range chain {
+		if !slices.Contains(unique, compiler) {
+			unique = append(unique, compiler)
+		}
+	}
+	return unique
+}
+
+// resolveCompiler 按编译器链选择第一个支持该目标的编译器
+func (p *buildParam) resolveCompiler() error {
+	chain := compilerChain(p.CompilerChains, p.Compiler, p.Target)
+	for index, compiler := range chain {
+		if compiler == compilerAuto {
+			compiler = resolveAutoCompiler(p.goos, p.goarch)
+			fmt.Printf("Compiler\t:\t %s: auto => %s\n", p.Target, compiler)
+		}
+		if !compilerSupports(compiler, p.goos, p.goarch) {
+			fmt.Printf("Fallback\t:\t %s: compiler %q does not support this target\n", p.Target, compiler)
+			continue
+		}
+		if index > 0 {
+			fmt.Printf("Fallback\t:\t %s: use compiler %q instead of %q\n", p.Target, compiler, chain[0])
+		}
+		p.Compiler = compiler
+		p.applyCompilerRule()
+		return nil
+	}
+	return fmt.Errorf(`no compiler in %v supports target %q`, chain, p.Target)
+}
+
+// applyCompilerRule 应用当前编译器的构建标签替换和 CGO 设置
+func (p *buildParam) applyCompilerRule() {
+	rule, ok := p.CompilerRules[p.Compiler]
+	if !ok {
+		return
+	}
+	if len(rule.Tags) > 0 {
+		tags := make([]string, 0, len(p.BuildTags))
+		for _, tag := range p.BuildTags {
+			newTag, ok := rule.Tags[tag]
+			if !ok {
+				tags = append(tags, tag)
+				continue
+			}
+			if len(newTag) == 0 {
+				fmt.Printf("Fallback\t:\t %s: remove build tag %q for compiler %q\n", p.Target, tag, p.Compiler)
+				continue
+			}
+			fmt.Printf("Fallback\t:\t %s: replace build tag %q with %q for compiler %q\n", p.Target, tag, newTag, p.Compiler)
+			if !slices.Contains(tags, newTag) {
+				tags = append(tags, newTag)
+			}
+		}
+		p.BuildTags = tags
+	}
+	if rule.CgoEnabled != nil && *rule.CgoEnabled != p.CgoEnabled {
+		fmt.Printf("Fallback\t:\t %s: set CgoEnabled to %v for compiler %q\n", p.Target, *rule.CgoEnabled, p.Compiler)
+		p.CgoEnabled = *rule.CgoEnabled
+	}
+}
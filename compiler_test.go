@@ -0,0 +1,51 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompilerFallback(t *testing.T) {
+	cgoEnabled := true
+	p := buildParam{
+		Config: Config{
+			Compiler:       `xgo`,
+			BuildTags:      []string{`bindata`, `db_sqlite`, `sqlitecgo`},
+			CompilerChains: defaultCompilerChains,
+			CompilerRules: map[string]CompilerRule{
+				`go`: {Tags: map[string]string{`sqlitecgo`: `sqlite`}, CgoEnabled: &cgoEnabled},
+			},
+		},
+		Target: `freebsd/amd64`,
+		goos:   `freebsd`,
+		goarch: `amd64`,
+	}
+	assert.NoError(t, p.resolveCompiler())
+	assert.Equal(t, `go`, p.Compiler)
+	assert.Equal(t, []string{`bindata`, `db_sqlite`, `sqlite`}, p.BuildTags)
+	assert.True(t, p.CgoEnabled)
+
+	p.Compiler = `xgo`
+	p.Target = `linux/arm64`
+	p.goos = `linux`
+	p.goarch = `arm64`
+	p.BuildTags = []string{`sqlitecgo`}
+	assert.NoError(t, p.resolveCompiler())
+	assert.Equal(t, `xgo`, p.Compiler)
+	assert.Equal(t, []string{`sqlitecgo`}, p.BuildTags)
+}
+
+func TestCompilerChain(t *testing.T) {
+	chains := map[string][]string{
+		`*`:             {`xgo`, `go`},
+		`linux/*`:       {`go`},
+		`linux/riscv64`: {`xgo`},
+	}
+	assert.Equal(t, []string{`xgo`, `go`}, compilerChain(chains, ``, `darwin/arm64`))
+	assert.Equal(t, []string{`go`}, compilerChain(chains, ``, `linux/amd64`))
+	assert.Equal(t, []string{`xgo`}, compilerChain(chains, ``, `linux/riscv64`))
+	assert.Equal(t, []string{`auto`, `go`}, compilerChain(chains, `auto`, `linux/arm64`))
+	assert.Equal(t, `go`, resolveAutoCompiler(runtime.GOOS, runtime.GOARCH))
+}
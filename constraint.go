@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"go/build/constraint"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// 满足 unix 构建约束的操作系统
+var unixOS = []string{`aix`, `android`, `darwin`, `dragonfly`, `freebsd`, `hurd`, `illumos`, `ios`, `linux`, `netbsd`, `openbsd`, `solaris`}
+
+// 额外满足的操作系统构建约束
+var impliedOS = map[string]string{
+	`android`: `linux`,
+	`illumos`: `solaris`,
+	`ios`:     `darwin`,
+}
+
+// parseMiscDirKey 将 VendorMiscDirs 的 key 解析为构建约束表达式
+func parseMiscDirKey(key string) (constraint.Expr, error) {
+	expr, err := constraint.Parse(`//go:build ` + key)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid VendorMiscDirs key %q: %w`, key, err)
+	}
+	return expr, nil
+}
+
+// evalMiscDirKey 判断构建约束在指定平台及标签下是否成立
+func evalMiscDirKey(expr constraint.Expr, osName string, archName string, tags []string) bool {
+	archName = strings.SplitN(archName, `-`, 2)[0]
+	return expr.Eval(func(tag string) bool {
+		switch tag {
+		case osName, archName:
+			return true
+		case `unix`:
+			return slices.Contains(unixOS, osName)
+		}
+		if implied, ok := impliedOS[osName]; ok && implied == tag {
+			return true
+		}
+		return slices.Contains(tags, tag)
+	})
+}
+
+// platformTags 返回目标注册表中所有的操作系统和架构
+func platformTags() map[string]struct{} {
+	tags := map[string]struct{}{`unix`: {}}
+	for _, t := range getTargetRegistry() {
+		tags[t.OS] = struct{}{}
+		tags[strings.SplitN(t.Arch, `-`, 2)[0]] = struct{}{}
+	}
+	return tags
+}
+
+// findOverlap 查找能同时满足两个构建约束的目标平台。每个平台按构建时使用的标签计算(go generate 与 go build 使用相同的标签)
+func findOverlap(a constraint.Expr, b constraint.Expr, tags []string) (string, bool) {
+	seen := map[string]struct{}{}
+	for _, t := range getTargetRegistry() {
+		arch := strings.SplitN(t.Arch, `-`, 2)[0]
+		platform := t.OS + `/` + arch
+		if _, ok := seen[platform]; ok {
+			continue
+		}
+		seen[platform] = struct{}{}
+		platformTags := targetPureGoTags(slices.Clone(tags), t.OS)
+		if evalMiscDirKey(a, t.OS, arch, platformTags) && evalMiscDirKey(b, t.OS, arch, platformTags) {
+			return platform + ` -tags ` + strings.Join(platformTags, `,`), true
+		}
+	}
+	return ``, false
+}
+
+// checkMiscDirKeys 校验 VendorMiscDirs 的 key，并检查各个 key 的构建约束在使用 tags 构建时是否存在重叠
+func checkMiscDirKeys(keys []string, tags []string) error {
+	exprs := make([]constraint.Expr, len(keys))
+	for index, key := range keys {
+		expr, err := parseMiscDirKey(key)
+		if err != nil {
+			return err
+		}
+		exprs[index] = expr
+	}
+	for i := 0; i < len(keys); i++ {
+		for j := i + 1; j < len(keys); j++ {
+			if example, ok := findOverlap(exprs[i], exprs[j], tags); ok {
+				return fmt.Errorf(`VendorMiscDirs keys %q and %q overlap (e.g. %s)`, keys[i], keys[j], example)
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	simpleKeyRegexp  = regexp.MustCompile(`^!?[A-Za-z0-9_.]+$`)
+	fileSuffixRegexp = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// miscDirKeyFileSuffix 将 VendorMiscDirs 的 key 转换为文件名后缀，例如: linux => linux、!linux => nonlinux、
+// `linux && (amd64 || arm64)` => linux_and_amd64_or_arm64_<hash>。
+// 为避免文件名后缀被 Go 当作隐含的 GOOS/GOARCH 约束以及不同表达式生成相同的文件名，复杂表达式会附加哈希值
+func miscDirKeyFileSuffix(key string) string {
+	key = strings.TrimSpace(key)
+	if simpleKeyRegexp.MatchString(key) {
+		suffix := strings.ReplaceAll(key, `.`, `_`)
+		if strings.HasPrefix(key, `!`) {
+			suffix = `non` + strings.TrimPrefix(suffix, `!`)
+		}
+		parts := strings.Split(suffix, `_`)
+		last := parts[len(parts)-1]
+		_, isPlatform := platformTags()[last]
+		if (len(parts) == 1 && !strings.HasPrefix(key, `!`)) || (!isPlatform && last != `test`) {
+			return suffix
+		}
+	}
+	name := strings.NewReplacer(`&&`, `_and_`, `||`, `_or_`, `!`, `non`).Replace(key)
+	name = strings.Trim(fileSuffixRegexp.ReplaceAllString(name, `_`), `_`)
+	sum := sha1.Sum([]byte(normalizeMiscDirKey(key)))
+	return name + `_` + hex.EncodeToString(sum[:4])
+}
+
+// normalizeMiscDirKey 返回格式化后的构建约束表达式，解析失败时原样返回
+func normalizeMiscDirKey(key string) string {
+	expr, err := parseMiscDirKey(key)
+	if err != nil {
+		return strings.TrimSpace(key)
+	}
+	return expr.String()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiscDirKeyFileSuffix(t *testing.T) {
+	assert.Equal(t, `linux`, miscDirKeyFileSuffix(`linux`))
+	assert.Equal(t, `nonlinux`, miscDirKeyFileSuffix(`!linux`))
+	assert.Equal(t, `db_mysql`, miscDirKeyFileSuffix(`db_mysql`))
+
+	suffix := miscDirKeyFileSuffix(`linux && (amd64 || arm64)`)
+	assert.True(t, strings.HasPrefix(suffix, `linux_and_amd64_or_arm64_`))
+	assert.Len(t, suffix, len(`linux_and_amd64_or_arm64_`)+8)
+	assert.Equal(t, suffix, miscDirKeyFileSuffix(`linux&&(amd64||arm64)`))
+	assert.NotEqual(t, miscDirKeyFileSuffix(`(linux || darwin) && arm64`), miscDirKeyFileSuffix(`linux || (darwin && arm64)`))
+
+	// 自定义标签以平台名称结尾时，避免被当作隐含的 GOARCH 约束
+	assert.NotEqual(t, `custom_amd64`, miscDirKeyFileSuffix(`custom_amd64`))
+}
+
+func TestCheckMiscDirKeys(t *testing.T) {
+	assert.NoError(t, checkMiscDirKeys([]string{`linux`, `!linux`}, nil))
+	assert.NoError(t, checkMiscDirKeys([]string{`linux && (amd64 || arm64)`, `linux && !amd64 && !arm64`, `!linux`}, nil))
+	assert.NoError(t, checkMiscDirKeys([]string{`db_mysql`, `!db_mysql`}, nil))
+	assert.NoError(t, checkMiscDirKeys([]string{`db_mysql`, `!db_mysql`}, []string{`db_mysql`}))
+	// 未启用的标签不会与平台重叠
+	assert.NoError(t, checkMiscDirKeys([]string{`linux`, `!linux`, `db_mysql`}, []string{`bindata`}))
+	assert.NoError(t, checkMiscDirKeys([]string{`linux`, `!netgo`}, nil))
+
+	err := checkMiscDirKeys([]string{`linux`, `unix && !darwin`}, nil)
+	assert.ErrorContains(t, err, `overlap`)
+	err = checkMiscDirKeys([]string{`linux`, `db_mysql`}, []string{`db_mysql`})
+	assert.ErrorContains(t, err, `overlap (e.g. `)
+	assert.ErrorContains(t, err, `db_mysql`)
+	err = checkMiscDirKeys([]string{`linux &&`}, nil)
+	assert.ErrorContains(t, err, `invalid VendorMiscDirs key`)
+}
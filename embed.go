@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/webx-top/com"
+)
+
+// 资源生成方式
+const (
+	assetGeneratorBindata = `bindata` // 通过 go-bindata 生成 bindata_assetfs.go
+	assetGeneratorEmbed   = `embed`   // 将资源复制到 embedAssetsDir 并生成包含 //go:embed 指令的 Go 文件
+)
+
+// embed 模式下存放资源副本的目录(相对于项目目录)
+const embedAssetsDir = `embed_assets`
+
+// embedBuildExpr 组合 VendorMiscDirs 的 key 与 Bindata.Tags 构建标签
+func embedBuildExpr(osName string) string {
+	tags := p.Bindata.WithDefaults().Tags
+	osName = normalizeMiscDirKey(osName)
+	if strings.ContainsAny(osName, `&|() `) {
+		osName = `(` + osName + `)`
+	}
+	if strings.ContainsAny(tags, `&|() `) {
+		tags = `(` + tags + `)`
+	}
+	return osName + ` && ` + tags
+}
+
+// embedGoFileName embed 模式下生成的 Go 文件名，例如: embed_assets_linux.go
+func embedGoFileName(suffix string) string {
+	return embedAssetsDir + `_` + suffix + `.go`
+}
+
+// isEmbedGoFile 是否为 embed 模式下生成的 Go 文件
+func isEmbedGoFile(file string) bool {
+	return strings.HasPrefix(filepath.Base(file), embedAssetsDir+`_`)
+}
+
+// embedGoSource 生成包含 //go:embed 指令的 Go 文件。
+// EmbeddedAssets 中的资源路径与 go-bindata 去除前缀后的路径一致
+func embedGoSource(osName string, hasAssets bool) string {
+	dir := path.Join(embedAssetsDir, miscDirKeyFileSuffix(osName))
+	src := "//go:build " + embedBuildExpr(osName) + "\n\n"
+	src += generatedCodeComment + "\n\n"
+	src += "package main\n\n"
+	src += "import (\n\t\"embed\"\n\t\"io/fs\"\n)\n\n"
+	if !hasAssets {
+		src += "// EmbeddedAssets 打包的资源文件\n"
+		src += "var EmbeddedAssets fs.FS = embed.FS{}\n"
+		return src
+	}
+	src += "//go:embed all:" + dir + "\n"
+	src += "var embeddedAssets embed.FS\n\n"
+	src += "// EmbeddedAssets 打包的资源文件\n"
+	src += "var EmbeddedAssets, _ = fs.Sub(embeddedAssets, `" + dir + "`)\n"
+	return src
+}
+
+// stageEmbedAssets 将资源文件复制到 embed 目录中与资源路径相同的位置
+func stageEmbedAssets(projectPath string, osName string, files []assetFile) error {
+	stageDir := filepath.Join(projectPath, embedAssetsDir, miscDirKeyFileSuffix(osName))
+	err := os.RemoveAll(stageDir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		source := filepath.FromSlash(file.Source)
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(projectPath, source)
+		}
+		dest := filepath.Join(stageDir, filepath.FromSlash(file.Name))
+		err = com.MkdirAll(filepath.Dir(dest), os.ModePerm)
+		if err != nil {
+			return err
+		}
+		err = com.Copy(source, dest)
+		if err != nil {
+			return fmt.Errorf(`failed to copy %s: %w`, file.Source, err)
+		}
+	}
+	return nil
+}
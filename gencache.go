@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/webx-top/com"
+)
+
+// defaultCacheDir 返回默认的缓存目录
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, `nging-builder`)
+}
+
+// cacheDir 返回缓存目录
+func (p buildParam) cacheDir() string {
+	if len(p.CacheDir) > 0 {
+		return p.CacheDir
+	}
+	return defaultCacheDir()
+}
+
+// generateCache 缓存 go generate 生成的资源文件(Bindata.Output)。
+// 生成结果只取决于目标平台满足的 VendorMiscDirs key 对应的 main_<suffix>.go 以及这些资源目录中的文件，
+// 因此相同 key 的目标(通常是同一 GOOS 的所有目标)只需执行一次 go generate
+type generateCache struct {
+	dir            string              // 持久缓存目录，为空时只在本次运行中复用
+	vendorMiscDirs map[string][]string // 合并了插件资源目录后的 VendorMiscDirs
+	keys           map[string]string   // key: 目标平台满足的 VendorMiscDirs key; value: 缓存 key
+	current        string              // 项目目录中当前生成文件对应的缓存 key
+}
+
+func newGenerateCache(dir string, vendorMiscDirs map[string][]string) *generateCache {
+	return &generateCache{
+		dir:            dir,
+		vendorMiscDirs: vendorMiscDirs,
+		keys:           map[string]string{},
+	}
+}
+
+// matchedMiscDirKeys 返回目标平台及构建标签满足的 VendorMiscDirs key
+func (g *generateCache) matchedMiscDirKeys(goos string, goarch string, tags []string) ([]string, error) {
+	var matched []string
+	for _, key := range miscDirKeys(g.vendorMiscDirs) {
+		expr, err := parseMiscDirKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if evalMiscDirKey(expr, goos, goarch, tags) {
+			matched = append(matched, key)
+		}
+	}
+	return matched, nil
+}
+
+func hashFile(h hash.Hash, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
+
+// key 计算目标的缓存 key: GOOS、资源生成工具配置、main_<suffix>.go 文件内容以及资源文件的路径和内容
+func (g *generateCache) key(ctx context.Context, p buildParam) (string, error) {
+	matched, err := g.matchedMiscDirKeys(p.goos, p.goarch, p.buildTags())
+	if err != nil {
+		return ``, err
+	}
+	signature := p.goos + `|` + strings.Join(matched, `|`)
+	if key, ok := g.keys[signature]; ok {
+		return key, nil
+	}
+	h := sha256.New()
+	b := p.Bindata.WithDefaults()
+	fmt.Fprintf(h, "goos=%s\ngenerator=%s\nbindata=%s@%s %s\nlevel=%d\n", p.goos, p.AssetGenerator, b.Module, b.Version, b.Output, p.BindataLevel)
+	for _, key := range matched {
+		file := filepath.Join(p.ProjectPath, `main_`+miscDirKeyFileSuffix(key)+`.go`)
+		fmt.Fprintf(h, "file=%s\n", filepath.Base(file))
+		if err = hashFile(h, file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return ``, err
+		}
+		prefixes, miscDirs := genMiscDirs(ctx, miscDirsOfKey(g.vendorMiscDirs, key)...)
+		assets, err := collectAssetFiles(p.ProjectPath, prefixes, miscDirs, p.BindataIgnore)
+		if err != nil {
+			return ``, err
+		}
+		for _, asset := range assets {
+			fmt.Fprintf(h, "asset=%s=%s\n", asset.Source, asset.Name)
+			source := filepath.FromSlash(asset.Source)
+			if !filepath.IsAbs(source) {
+				source = filepath.Join(p.ProjectPath, source)
+			}
+			if err = hashFile(h, source); err != nil {
+				return ``, err
+			}
+		}
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	g.keys[signature] = key
+	return key, nil
+}
+
+// outputFile 返回 go generate 生成的文件，embed 模式下为空
+func (g *generateCache) outputFile(p buildParam) string {
+	if p.AssetGenerator == assetGeneratorEmbed {
+		return ``
+	}
+	return filepath.Join(p.ProjectPath, p.Bindata.WithDefaults().Output)
+}
+
+func (g *generateCache) cachedFile(key string, output string) string {
+	return filepath.Join(g.dir, `generate`, key, filepath.Base(output))
+}
+
+// run 在缓存未命中时执行 go generate 并保存生成的文件，命中时恢复缓存的文件。
+// 缓存文件及恢复的文件都先写入临时文件再重命名，中断或多个进程共用缓存时不会留下不完整的文件
+func (g *generateCache) run(ctx context.Context, p buildParam, generate func(context.Context, buildParam)) error {
+	key, err := g.key(ctx, p)
+	if err != nil {
+		return err
+	}
+	if key == g.current {
+		fmt.Println(`Generate	:	 skip (unchanged)`, key[:12])
+		return nil
+	}
+	output := g.outputFile(p)
+	if len(output) > 0 && len(g.dir) > 0 {
+		cached := g.cachedFile(key, output)
+		if com.FileExists(cached) {
+			fmt.Println(`Generate	:	 restore from cache`, key[:12])
+			if err = copyFileAtomic(cached, output); err != nil {
+				return err
+			}
+			g.current = key
+			return nil
+		}
+	}
+	generate(ctx, p)
+	g.current = key
+	if len(output) == 0 || len(g.dir) == 0 {
+		return nil
+	}
+	cached := g.cachedFile(key, output)
+	if err = com.MkdirAll(filepath.Dir(cached), os.ModePerm); err != nil {
+		return err
+	}
+	if !com.FileExists(output) {
+		return nil
+	}
+	return copyFileAtomic(output, cached)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateCache(t *testing.T) {
+	original := p.Clone()
+	defer func() {
+		p = original
+	}()
+	root := t.TempDir()
+	cacheDir := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`template/index.html`:       `index`,
+		`vendor/x/template/a.html`:  `linux only`,
+		`main_linux.go`:             "//go:build linux\n\npackage main\n",
+		`main_nonlinux.go`:          "//go:build !linux\n\npackage main\n",
+		`public/assets/js/app.js`:   `js`,
+		`config/i18n/messages/a.go`: `ignored`,
+	})
+	p.ProjectPath = root
+	p.AssetDirs = nil
+	p.AutoDiscoveryMiscDir = false
+	p.AssetGenerator = ``
+	p.Bindata = BindataConfig{}
+	vendorMiscDirs := map[string][]string{
+		`*`:      {},
+		`linux`:  {`vendor/x/template/`},
+		`!linux`: {},
+	}
+	output := filepath.Join(root, `bindata_assetfs.go`)
+	var generated []string
+	generate := func(_ context.Context, bp buildParam) {
+		generated = append(generated, bp.goos+`/`+bp.goarch)
+		assert.NoError(t, os.WriteFile(output, []byte(`// `+bp.goos), 0666))
+	}
+	build := func(g *generateCache, targets ...string) {
+		for _, target := range targets {
+			bp := p.Clone()
+			bp.goos, bp.goarch = filepath.Dir(target), filepath.Base(target)
+			assert.NoError(t, g.run(context.Background(), bp, generate))
+			b, err := os.ReadFile(output)
+			assert.NoError(t, err)
+			assert.Equal(t, `// `+bp.goos, string(b))
+		}
+	}
+	targets := []string{`linux/amd64`, `linux/arm64`, `windows/amd64`, `darwin/arm64`, `linux/arm-7`}
+
+	build(newGenerateCache(cacheDir, vendorMiscDirs), targets...)
+	assert.Equal(t, []string{`linux/amd64`, `windows/amd64`, `darwin/arm64`}, generated)
+
+	// 再次运行时从缓存恢复
+	generated = nil
+	build(newGenerateCache(cacheDir, vendorMiscDirs), targets...)
+	assert.Empty(t, generated)
+
+	// 资源文件变化后重新生成
+	assert.NoError(t, os.WriteFile(filepath.Join(root, `vendor/x/template/a.html`), []byte(`changed`), 0666))
+	generated = nil
+	build(newGenerateCache(cacheDir, vendorMiscDirs), targets...)
+	assert.Equal(t, []string{`linux/amd64`}, generated)
+
+	// 被忽略的文件不影响缓存
+	assert.NoError(t, os.WriteFile(filepath.Join(root, `config/i18n/messages/a.go`), []byte(`changed`), 0666))
+	generated = nil
+	build(newGenerateCache(cacheDir, vendorMiscDirs), targets...)
+	assert.Empty(t, generated)
+
+	// 不使用持久缓存时，同一次运行中仍然只为每个 GOOS 执行一次
+	generated = nil
+	build(newGenerateCache(``, vendorMiscDirs), `linux/amd64`, `linux/arm64`, `linux/arm-7`)
+	assert.Equal(t, []string{`linux/amd64`}, generated)
+}
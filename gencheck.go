@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/webx-top/com"
+)
+
+// diffGeneratedFile 比较文件的期望内容与磁盘上的内容，相同时返回空字符串
+func diffGeneratedFile(file generatedFile) (string, error) {
+	b, err := os.ReadFile(file.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return ``, err
+	}
+	if string(b) == file.Content {
+		return ``, nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(b)),
+		B:        difflib.SplitLines(file.Content),
+		FromFile: file.Path + ` (on disk)`,
+		ToFile:   file.Path + ` (expected)`,
+		Context:  3,
+	})
+}
+
+// missingMiscDirs 返回 VendorMiscDirs 中在磁盘上不存在的资源目录
+func missingMiscDirs(ctx context.Context, vendorMiscDirs map[string][]string) []string {
+	var missing []string
+	keys := make([]string, 0, len(vendorMiscDirs))
+	for key := range vendorMiscDirs {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, dir := range vendorMiscDirs[key] {
+			realDir := dir
+			if isModuleMiscDir(dir) {
+				resolved, _, err := resolveModuleMiscDir(ctx, p.ProjectPath, dir)
+				if err != nil {
+					missing = append(missing, fmt.Sprintf(`[%s] %s (%v)`, key, dir, err))
+					continue
+				}
+				realDir = resolved
+			}
+			if !filepath.IsAbs(realDir) {
+				realDir = filepath.Join(p.ProjectPath, realDir)
+			}
+			if !com.IsDir(realDir) {
+				missing = append(missing, `[`+key+`] `+dir)
+			}
+		}
+	}
+	return missing
+}
+
+// checkGenerateCommandComment 检查 main_<os>.go 文件是否与期望内容一致，不一致时输出 unified diff
+func checkGenerateCommandComment(ctx context.Context, w io.Writer) bool {
+	vendorMiscDirs := vendorMiscDirsWithPlugins(ctx)
+	for _, dir := range missingMiscDirs(ctx, vendorMiscDirs) {
+		fmt.Fprintln(w, `Warning		:	 misc dir does not exist:`, dir)
+	}
+	files, err := buildGenerateCommandFiles(ctx, vendorMiscDirs)
+	if err != nil {
+		com.ExitOnFailure(err.Error(), 1)
+	}
+	upToDate := true
+	for _, file := range files {
+		diff, err := diffGeneratedFile(file)
+		if err != nil {
+			com.ExitOnFailure(err.Error(), 1)
+		}
+		if len(diff) == 0 {
+			fmt.Fprintln(w, `[up-to-date]	:	`, file.Path)
+			continue
+		}
+		upToDate = false
+		fmt.Fprintln(w, `[out-of-date]	:	`, file.Path)
+		fmt.Fprint(w, diff)
+	}
+	return upToDate
+}
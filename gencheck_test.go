@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffGeneratedFile(t *testing.T) {
+	file := generatedFile{
+		Path:    filepath.Join(t.TempDir(), `main_linux.go`),
+		Content: "//go:build linux\n\npackage main\n",
+	}
+	diff, err := diffGeneratedFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "+//go:build linux\n")
+
+	err = os.WriteFile(file.Path, []byte("//go:build linux\n\npackage main\n"), 0666)
+	assert.NoError(t, err)
+	diff, err = diffGeneratedFile(file)
+	assert.NoError(t, err)
+	assert.Empty(t, diff)
+
+	err = os.WriteFile(file.Path, []byte("//go:build darwin\n\npackage main\n"), 0666)
+	assert.NoError(t, err)
+	diff, err = diffGeneratedFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, diff, "-//go:build darwin\n+//go:build linux\n")
+}
+
+func TestMissingMiscDirs(t *testing.T) {
+	p.ProjectPath = t.TempDir()
+	defer func() {
+		p.ProjectPath = ``
+	}()
+	assert.NoError(t, os.MkdirAll(filepath.Join(p.ProjectPath, `vendor`, `a`, `template`), os.ModePerm))
+	missing := missingMiscDirs(t.Context(), map[string][]string{
+		`*`:     {`vendor/a/template/`},
+		`linux`: {`vendor/b/template/`},
+	})
+	assert.Equal(t, []string{`[linux] vendor/b/template/`}, missing)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strings"
+)
+
+// 标记由 builder 维护的 go:generate 指令块
+const (
+	generateBlockBegin = `//nging-builder:begin`
+	generateBlockEnd   = `//nging-builder:end`
+)
+
+// 由 builder 生成的 Go 文件中的标记注释
+const generatedCodeComment = `// Code generated by nging-builder. DO NOT EDIT.`
+
+// isLegacyGenerateDirective 是否为旧版本 builder 生成的(没有标记的) go:generate 指令
+func isLegacyGenerateDirective(text string) bool {
+	return strings.HasPrefix(text, `//go:generate go install github.com/admpub/bindata/`) ||
+		strings.HasPrefix(text, `//go:generate go-bindata `)
+}
+
+type sourceEdit struct {
+	start int
+	end   int
+	text  string
+}
+
+// lineEnd 返回 offset 所在行的结束位置(包含换行符)
+func lineEnd(src []byte, offset int) int {
+	if pos := bytes.IndexByte(src[offset:], '\n'); pos > -1 {
+		return offset + pos + 1
+	}
+	return len(src)
+}
+
+// rewriteGenerateFile 更新文件中由 builder 维护的 //go:build 行和 go:generate 指令块，
+// 保留包文档、import 以及其它指令，并使用 gofmt 格式化
+func rewriteGenerateFile(src []byte, buildExpr string, directives []string) ([]byte, error) {
+	block := generateBlockBegin + "\n"
+	if len(directives) > 0 {
+		block += strings.Join(directives, "\n") + "\n"
+	}
+	block += generateBlockEnd + "\n"
+	if len(bytes.TrimSpace(src)) == 0 {
+		src = []byte("package main\n")
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, ``, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+	packageOffset := offset(f.Package)
+	var edits []sourceEdit
+	var hasBuildLine bool
+	blockStart := -1
+	blockFound := false
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			start := offset(c.Pos())
+			switch {
+			case blockFound:
+				if isLegacyGenerateDirective(c.Text) {
+					edits = append(edits, sourceEdit{start: start, end: lineEnd(src, start)})
+				}
+			case blockStart > -1:
+				if c.Text == generateBlockEnd {
+					edits = append(edits, sourceEdit{start: blockStart, end: lineEnd(src, offset(c.End())), text: block})
+					blockFound = true
+				}
+			case c.Text == generateBlockBegin:
+				blockStart = start
+			case start < packageOffset && strings.HasPrefix(c.Text, `//go:build`):
+				edits = append(edits, sourceEdit{start: start, end: offset(c.End()), text: `//go:build ` + buildExpr})
+				hasBuildLine = true
+			case start < packageOffset && strings.HasPrefix(c.Text, `// +build`):
+				edits = append(edits, sourceEdit{start: start, end: lineEnd(src, start)})
+			case isLegacyGenerateDirective(c.Text):
+				edits = append(edits, sourceEdit{start: start, end: lineEnd(src, start)})
+			}
+		}
+	}
+	if !blockFound {
+		if blockStart > -1 { // 缺少结束标记
+			edits = append(edits, sourceEdit{start: blockStart, end: lineEnd(src, blockStart)})
+		}
+		edits = append(edits, sourceEdit{start: offset(f.Name.End()), end: offset(f.Name.End()), text: "\n\n" + block})
+	}
+	if !hasBuildLine {
+		edits = append(edits, sourceEdit{start: 0, end: 0, text: `//go:build ` + buildExpr + "\n\n"})
+	}
+	slices.SortStableFunc(edits, func(a, b sourceEdit) int {
+		return b.start - a.start
+	})
+	result := slices.Clone(src)
+	for _, e := range edits {
+		result = slices.Concat(result[:e.start], []byte(e.text), result[e.end:])
+	}
+	return format.Source(result)
+}
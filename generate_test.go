@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteGenerateFile(t *testing.T) {
+	directives := []string{`//go:generate echo a`, `//go:generate echo b`}
+
+	b, err := rewriteGenerateFile(nil, `linux`, directives)
+	assert.NoError(t, err)
+	assert.Equal(t, `//go:build linux
+
+package main
+
+//nging-builder:begin
+//go:generate echo a
+//go:generate echo b
+//nging-builder:end
+`, string(b))
+
+	// 旧版本生成的文件: 没有 import、注释中包含 import、手动添加的指令
+	legacy := `//go:build darwin
+
+// Package main 不要 import 这个包
+package main
+
+//go:generate go install github.com/admpub/bindata/v3/go-bindata@latest
+//go:generate go-bindata -fs -o bindata_assetfs.go template/...
+
+//go:generate echo custom
+
+func init() {}
+`
+	b, err = rewriteGenerateFile([]byte(legacy), `linux`, directives)
+	assert.NoError(t, err)
+	expected := `//go:build linux
+
+// Package main 不要 import 这个包
+package main
+
+//nging-builder:begin
+//go:generate echo a
+//go:generate echo b
+//nging-builder:end
+
+//go:generate echo custom
+
+func init() {}
+`
+	assert.Equal(t, expected, string(b))
+
+	// 再次生成时只替换标记之间的指令
+	b, err = rewriteGenerateFile(b, `linux`, []string{`//go:generate echo c`})
+	assert.NoError(t, err)
+	assert.Equal(t, `//go:build linux
+
+// Package main 不要 import 这个包
+package main
+
+//nging-builder:begin
+//go:generate echo c
+//nging-builder:end
+
+//go:generate echo custom
+
+func init() {}
+`, string(b))
+
+	_, err = rewriteGenerateFile([]byte(`package main; func {`), `linux`, directives)
+	assert.Error(t, err)
+}
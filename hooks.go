@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+
+	"github.com/webx-top/com"
+)
+
+// 钩子阶段
+const (
+	hookBeforeGenerate  = `BeforeGenerate`
+	hookAfterGenerate   = `AfterGenerate`
+	hookBeforeBuild     = `BeforeBuild`
+	hookAfterBuild      = `AfterBuild`
+	hookBeforePack      = `BeforePack`
+	hookAfterPack       = `AfterPack`
+	hookBeforeChecksums = `BeforeChecksums`
+	hookAfterChecksums  = `AfterChecksums`
+)
+
+// Hook 钩子命令
+type Hook struct {
+	Command      string // 通过 shell 执行的命令
+	Dir          string // 工作目录，为空时采用项目目录
+	AllowFailure bool   // 执行失败时是否继续构建
+}
+
+// Hooks 各构建阶段的钩子命令。
+// 合并生成 checksums.txt 时，Checksums 阶段在所有目标构建完毕后执行一次，此时只执行全局钩子。
+// 命中构建缓存时不执行 Generate 和 Build 阶段的钩子，AfterBuild 钩子对发布目录中可执行文件的修改会一并缓存
+type Hooks struct {
+	BeforeGenerate  []Hook
+	AfterGenerate   []Hook
+	BeforeBuild     []Hook
+	AfterBuild      []Hook
+	BeforePack      []Hook
+	AfterPack       []Hook
+	BeforeChecksums []Hook
+	AfterChecksums  []Hook
+}
+
+func (h Hooks) Clone() Hooks {
+	return Hooks{
+		BeforeGenerate:  slices.Clone(h.BeforeGenerate),
+		AfterGenerate:   slices.Clone(h.AfterGenerate),
+		BeforeBuild:     slices.Clone(h.BeforeBuild),
+		AfterBuild:      slices.Clone(h.AfterBuild),
+		BeforePack:      slices.Clone(h.BeforePack),
+		AfterPack:       slices.Clone(h.AfterPack),
+		BeforeChecksums: slices.Clone(h.BeforeChecksums),
+		AfterChecksums:  slices.Clone(h.AfterChecksums),
+	}
+}
+
+// Stage 返回指定阶段的钩子
+func (h Hooks) Stage(stage string) []Hook {
+	switch stage {
+	case hookBeforeGenerate:
+		return h.BeforeGenerate
+	case hookAfterGenerate:
+		return h.AfterGenerate
+	case hookBeforeBuild:
+		return h.BeforeBuild
+	case hookAfterBuild:
+		return h.AfterBuild
+	case hookBeforePack:
+		return h.BeforePack
+	case hookAfterPack:
+		return h.AfterPack
+	case hookBeforeChecksums:
+		return h.BeforeChecksums
+	case hookAfterChecksums:
+		return h.AfterChecksums
+	default:
+		return nil
+	}
+}
+
+// stageHooks 返回全局钩子及与当前目标匹配的目标钩子
+func (p buildParam) stageHooks(stage string) []Hook {
+	hooks := slices.Clone(p.Hooks.Stage(stage))
+	if len(p.Target) == 0 {
+		return hooks
+	}
+	patterns := make([]string, 0, len(p.TargetHooks))
+	for pattern := range p.TargetHooks {
+		if matchTarget(pattern, p.Target) || matchTarget(pattern, targetName(p.Target)) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	slices.Sort(patterns)
+	for _, pattern := range patterns {
+		hooks = append(hooks, p.TargetHooks[pattern].Stage(stage)...)
+	}
+	return hooks
+}
+
+// hookEnvVars 生成传递给钩子命令的环境变量
+func (p buildParam) hookEnvVars(stage string, archive string) []string {
+	env := []string{
+		`BUILDER_STAGE=` + stage,
+		`BUILDER_EXECUTOR=` + p.Executor,
+		`BUILDER_VERSION=` + p.NgingVersion,
+		`BUILDER_COMMIT=` + p.NgingCommitID,
+		`BUILDER_PROJECT_PATH=` + p.ProjectPath,
+		`BUILDER_TARGET=` + p.Target,
+		`BUILDER_GOOS=` + p.goos,
+		`BUILDER_GOARCH=` + p.goarch,
+		`BUILDER_RELEASE_DIR=` + p.ReleaseDir,
+		`BUILDER_ARCHIVE=` + archive,
+	}
+	if len(archive) > 0 {
+		env = append(env, `BUILDER_PACKED_DIR=`+filepath.Dir(archive))
+	}
+	return env
+}
+
+func hookCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == `windows` {
+		return newCommand(ctx, `cmd`, `/C`, command)
+	}
+	return newCommand(ctx, `sh`, `-c`, command)
+}
+
+// runHooks 执行指定阶段的钩子。archive 为压缩包或 checksums.txt 文件路径(没有时为空)
+func (p buildParam) runHooks(ctx context.Context, stage string, archive string) {
+	for _, hook := range p.stageHooks(stage) {
+		if len(hook.Command) == 0 {
+			continue
+		}
+		fmt.Printf("Hook		:	 [%s] %s\n", stage, hook.Command)
+		hookCtx, cancel := stageContext(ctx, `hook`, p.Timeouts.Hook)
+		cmd := hookCommand(hookCtx, hook.Command)
+		cmd.Dir = hook.Dir
+		if len(cmd.Dir) == 0 {
+			cmd.Dir = p.ProjectPath
+		}
+		cmd.Stderr = os.Stderr
+		cmd.Stdout = os.Stdout
+		cmd.Env = append(cmd.Env, os.Environ()...)
+		cmd.Env = append(cmd.Env, p.hookEnvVars(stage, archive)...)
+		err := cmd.Run()
+		if err != nil && hookCtx.Err() != nil && ctx.Err() == nil {
+			err = context.Cause(hookCtx)
+		}
+		cancel()
+		if err == nil {
+			continue
+		}
+		exitIfCanceled(ctx)
+		if hook.AllowFailure {
+			fmt.Printf("Warning		:	 [%s] hook %q failed: %v\n", stage, hook.Command, err)
+			continue
+		}
+		com.ExitOnFailure(fmt.Sprintf("Error		:	 [%s] hook %q failed: %v\n", stage, hook.Command, err), 1)
+	}
+}
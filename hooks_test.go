@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStageHooks(t *testing.T) {
+	p := buildParam{
+		Config: Config{
+			Hooks: Hooks{BeforePack: []Hook{{Command: `global`}}},
+			TargetHooks: map[string]Hooks{
+				`linux/*`:     {BeforePack: []Hook{{Command: `linux`}}},
+				`windows_386`: {BeforePack: []Hook{{Command: `windows`}}},
+			},
+		},
+		Target: `linux/amd64`,
+	}
+	assert.Equal(t, []Hook{{Command: `global`}, {Command: `linux`}}, p.stageHooks(hookBeforePack))
+	p.Target = `windows/386`
+	assert.Equal(t, []Hook{{Command: `global`}, {Command: `windows`}}, p.stageHooks(hookBeforePack))
+	assert.Empty(t, p.stageHooks(hookAfterPack))
+}
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == `windows` {
+		t.Skip(`requires sh`)
+	}
+	dir := t.TempDir()
+	p := buildParam{
+		Config: Config{
+			NgingVersion: `5.3.0`,
+			Hooks: Hooks{AfterPack: []Hook{
+				{Command: `exit 1`, AllowFailure: true},
+				{Command: `echo "$BUILDER_TARGET $BUILDER_VERSION $BUILDER_ARCHIVE" > hook.txt`},
+			}},
+		},
+		Target:      `linux/amd64`,
+		ProjectPath: dir,
+	}
+	p.runHooks(context.Background(), hookAfterPack, `/dist/nging_linux_amd64.tar.gz`)
+	b, err := os.ReadFile(filepath.Join(dir, `hook.txt`))
+	assert.NoError(t, err)
+	assert.Equal(t, "linux/amd64 5.3.0 /dist/nging_linux_amd64.tar.gz\n", string(b))
+}
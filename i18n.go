@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// I18nConfig 语言文件校验配置
+type I18nConfig struct {
+	Validate        bool   // 生成 go:generate 文件前校验语言文件
+	Strict          bool   // 存在解析错误或缺失、多余的 key 时中止构建
+	Dir             string // 语言文件目录(相对于项目目录或插件目录)，默认: config/i18n
+	ReferenceLocale string // 作为参照的语言，默认: zh-cn
+}
+
+// 默认的语言文件校验配置
+var defaultI18nConfig = I18nConfig{
+	Dir:             `config/i18n`,
+	ReferenceLocale: `zh-cn`,
+}
+
+// WithDefaults 为未设置的项填充默认值
+func (c I18nConfig) WithDefaults() I18nConfig {
+	if len(c.Dir) == 0 {
+		c.Dir = defaultI18nConfig.Dir
+	}
+	if len(c.ReferenceLocale) == 0 {
+		c.ReferenceLocale = defaultI18nConfig.ReferenceLocale
+	}
+	c.Dir = strings.Trim(path.Clean(c.Dir), `/`)
+	c.ReferenceLocale = strings.ToLower(c.ReferenceLocale)
+	return c
+}
+
+// 支持的语言文件格式
+var i18nFileExtensions = []string{`.yaml`, `.yml`, `.json`}
+
+// i18nIssue 语言文件的问题
+type i18nIssue struct {
+	File    string   // 语言文件路径
+	Locale  string   // 语言
+	Missing []string // 参照语言中存在而当前语言中缺失的 key
+	Extra   []string // 当前语言中存在而参照语言中没有的 key
+	Err     error    // 解析错误
+}
+
+func (i i18nIssue) String() string {
+	if i.Err != nil {
+		return fmt.Sprintf(`%s: parse error: %v`, i.File, i.Err)
+	}
+	var parts []string
+	if len(i.Missing) > 0 {
+		parts = append(parts, fmt.Sprintf(`missing %d key(s): %s`, len(i.Missing), strings.Join(i.Missing, `, `)))
+	}
+	if len(i.Extra) > 0 {
+		parts = append(parts, fmt.Sprintf(`extra %d key(s): %s`, len(i.Extra), strings.Join(i.Extra, `, `)))
+	}
+	return i.File + `: ` + strings.Join(parts, `; `)
+}
+
+// flattenI18nMessages 将嵌套的语言文件内容展开为以 `.` 连接的 key
+func flattenI18nMessages(prefix string, data map[string]interface{}, keys map[string]struct{}) {
+	for key, value := range data {
+		if len(prefix) > 0 {
+			key = prefix + `.` + key
+		}
+		if m, ok := value.(map[string]interface{}); ok {
+			flattenI18nMessages(key, m, keys)
+			continue
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// parseI18nFile 解析语言文件并返回其中所有的 key
+func parseI18nFile(file string) (map[string]struct{}, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{}
+	if filepath.Ext(file) == `.json` {
+		err = json.Unmarshal(b, &data)
+	} else {
+		err = yaml.Unmarshal(b, &data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]struct{}{}
+	flattenI18nMessages(``, data, keys)
+	return keys, nil
+}
+
+// diffI18nKeys 返回 keys 相对于 reference 缺失和多余的 key
+func diffI18nKeys(reference map[string]struct{}, keys map[string]struct{}) (missing []string, extra []string) {
+	for key := range reference {
+		if _, ok := keys[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	for key := range keys {
+		if _, ok := reference[key]; !ok {
+			extra = append(extra, key)
+		}
+	}
+	slices.Sort(missing)
+	slices.Sort(extra)
+	return
+}
+
+// validateI18nDir 校验语言文件目录。同一目录下的语言文件(文件名为语言，例如 messages/en.yaml)与参照语言的文件进行比较，
+// 目录中没有参照语言的文件时仅检查解析错误
+func validateI18nDir(root string, displayDir string, referenceLocale string) ([]i18nIssue, error) {
+	groups := map[string][]string{}
+	var groupNames []string
+	err := filepath.WalkDir(root, func(fpath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if fpath == root && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || !slices.Contains(i18nFileExtensions, strings.ToLower(filepath.Ext(fpath))) {
+			return nil
+		}
+		dir := filepath.Dir(fpath)
+		if _, ok := groups[dir]; !ok {
+			groupNames = append(groupNames, dir)
+		}
+		groups[dir] = append(groups[dir], fpath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var issues []i18nIssue
+	for _, dir := range groupNames {
+		locales := map[string]map[string]struct{}{}
+		var files []string
+		fileLocales := map[string]string{}
+		for _, file := range groups[dir] {
+			rel, _ := filepath.Rel(root, file)
+			display := path.Join(displayDir, filepath.ToSlash(rel))
+			locale := strings.ToLower(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+			keys, err := parseI18nFile(file)
+			if err != nil {
+				issues = append(issues, i18nIssue{File: display, Locale: locale, Err: err})
+				continue
+			}
+			locales[locale] = keys
+			files = append(files, display)
+			fileLocales[display] = locale
+		}
+		reference, ok := locales[referenceLocale]
+		if !ok {
+			continue
+		}
+		for _, file := range files {
+			locale := fileLocales[file]
+			if locale == referenceLocale {
+				continue
+			}
+			missing, extra := diffI18nKeys(reference, locales[locale])
+			if len(missing) > 0 || len(extra) > 0 {
+				issues = append(issues, i18nIssue{File: file, Locale: locale, Missing: missing, Extra: extra})
+			}
+		}
+	}
+	return issues, nil
+}
+
+// checkI18nMessages 校验所有语言文件目录并输出问题，返回是否没有问题
+func checkI18nMessages(ctx context.Context, vendorMiscDirs map[string][]string, w io.Writer) (bool, error) {
+	cfg := p.I18n.WithDefaults()
+	valid := true
+	for _, dir := range miscDirsNamed(ctx, vendorMiscDirs, cfg.Dir) {
+		root := filepath.FromSlash(dir)
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(p.ProjectPath, root)
+		}
+		issues, err := validateI18nDir(root, dir, cfg.ReferenceLocale)
+		if err != nil {
+			return false, err
+		}
+		for _, issue := range issues {
+			fmt.Fprintln(w, `[i18n]		:	`, issue.String())
+		}
+		if len(issues) > 0 {
+			valid = false
+		}
+	}
+	return valid, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateI18nDir(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`messages/zh-cn.yaml`: "hello: 你好\nuser:\n  name: 用户名\n  email: 邮箱\n",
+		`messages/en.yaml`:    "hello: Hello\nuser:\n  name: Username\nbye: Bye\n",
+		`messages/ja.json`:    `{"hello": "こんにちは", "user": {"name": "ユーザー名", "email": "メール"}}`,
+		`messages/fr.yaml`:    "hello: [\n",
+		`rules/en.yaml`:       "plural: one\n",
+		`rules/readme.txt`:    `ignored`,
+	})
+	issues, err := validateI18nDir(root, `config/i18n`, `zh-cn`)
+	assert.NoError(t, err)
+	assert.Len(t, issues, 2)
+
+	assert.Equal(t, `config/i18n/messages/fr.yaml`, issues[0].File)
+	assert.Error(t, issues[0].Err)
+	assert.Contains(t, issues[0].String(), `config/i18n/messages/fr.yaml: parse error:`)
+
+	assert.Equal(t, `en`, issues[1].Locale)
+	assert.Equal(t, []string{`user.email`}, issues[1].Missing)
+	assert.Equal(t, []string{`bye`}, issues[1].Extra)
+	assert.Equal(t, `config/i18n/messages/en.yaml: missing 1 key(s): user.email; extra 1 key(s): bye`, issues[1].String())
+}
+
+func TestCheckI18nMessages(t *testing.T) {
+	original := p.Clone()
+	defer func() {
+		p = original
+	}()
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`config/i18n/messages/zh-cn.yaml`:                                           "hello: 你好\n",
+		`config/i18n/messages/en.yaml`:                                              "hello: Hello\n",
+		`vendor/github.com/nging-plugins/collector/config/i18n/messages/zh-cn.yaml`: "collector: 采集\n",
+		`vendor/github.com/nging-plugins/collector/config/i18n/messages/en.yaml`:    "other: Other\n",
+	})
+	p.ProjectPath = root
+	p.AssetDirs = nil
+	p.AutoDiscoveryMiscDir = false
+	p.I18n = I18nConfig{}
+	vendorMiscDirs := map[string][]string{
+		`*`:      {`vendor/github.com/nging-plugins/collector/template/`, `vendor/github.com/nging-plugins/collector/config/i18n/`},
+		`linux`:  {},
+		`!linux`: {},
+	}
+	assert.Equal(t, []string{
+		`vendor/github.com/nging-plugins/collector/config/i18n`,
+		`config/i18n`,
+	}, miscDirsNamed(context.Background(), vendorMiscDirs, `config/i18n`))
+
+	buf := bytes.NewBuffer(nil)
+	valid, err := checkI18nMessages(context.Background(), vendorMiscDirs, buf)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	assert.Contains(t, buf.String(), `vendor/github.com/nging-plugins/collector/config/i18n/messages/en.yaml: missing 1 key(s): collector; extra 1 key(s): other`)
+	assert.NotContains(t, buf.String(), "\tconfig/i18n/messages/en.yaml")
+
+	p.I18n.ReferenceLocale = `EN`
+	buf.Reset()
+	valid, err = checkI18nMessages(context.Background(), map[string][]string{`linux`: {}}, buf)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	assert.Empty(t, buf.String())
+}
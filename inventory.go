@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/webx-top/com"
+)
+
+// 项目自身资源文件(没有前缀)的分组名称
+const projectAssetGroup = `(project)`
+
+// assetEntry 资源清单中的文件
+type assetEntry struct {
+	Name           string `json:"name"`
+	Source         string `json:"source"`
+	Group          string `json:"group"`
+	Size           int64  `json:"size"`
+	CompressedSize int64  `json:"compressedSize"`
+}
+
+// assetGroup 按前缀(插件)汇总的资源大小
+type assetGroup struct {
+	Name           string `json:"name"`
+	Files          int    `json:"files"`
+	Size           int64  `json:"size"`
+	CompressedSize int64  `json:"compressedSize"`
+}
+
+// assetInventory VendorMiscDirs 中某个 key 的资源清单
+type assetInventory struct {
+	Key            string       `json:"key"`
+	Files          []assetEntry `json:"files"`
+	Groups         []assetGroup `json:"groups"`
+	Size           int64        `json:"size"`
+	CompressedSize int64        `json:"compressedSize"`
+}
+
+type countWriter int64
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	*c += countWriter(len(b))
+	return len(b), nil
+}
+
+// compressedSize 返回文件以指定级别 gzip 压缩后的大小
+func compressedSize(file string, level int) (int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var counter countWriter
+	w, err := gzip.NewWriterLevel(&counter, level)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = io.Copy(w, f); err != nil {
+		return 0, err
+	}
+	if err = w.Close(); err != nil {
+		return 0, err
+	}
+	return int64(counter), nil
+}
+
+// buildAssetInventory 统计每个 VendorMiscDirs key 需要打包的资源文件及其原始大小和压缩后的大小(BindataLevel)
+func buildAssetInventory(ctx context.Context, vendorMiscDirs map[string][]string) ([]assetInventory, error) {
+	var inventories []assetInventory
+	for _, key := range miscDirKeys(vendorMiscDirs) {
+		prefixes, miscDirs := genMiscDirs(ctx, miscDirsOfKey(vendorMiscDirs, key)...)
+		files, err := collectAssetFiles(p.ProjectPath, prefixes, miscDirs, p.BindataIgnore)
+		if err != nil {
+			return nil, err
+		}
+		inv := assetInventory{Key: normalizeMiscDirKey(key), Files: []assetEntry{}, Groups: []assetGroup{}}
+		groups := map[string]*assetGroup{}
+		var groupNames []string
+		for _, file := range files {
+			source := filepath.FromSlash(file.Source)
+			if !filepath.IsAbs(source) {
+				source = filepath.Join(p.ProjectPath, source)
+			}
+			fi, err := os.Stat(source)
+			if err != nil {
+				return nil, err
+			}
+			compressed, err := compressedSize(source, bindataCompressLevel(p.BindataLevel))
+			if err != nil {
+				return nil, fmt.Errorf(`%s: %w`, file.Source, err)
+			}
+			group := assetPrefix(file.Source, prefixes)
+			if len(group) == 0 {
+				group = projectAssetGroup
+			}
+			inv.Files = append(inv.Files, assetEntry{
+				Name:           file.Name,
+				Source:         file.Source,
+				Group:          group,
+				Size:           fi.Size(),
+				CompressedSize: compressed,
+			})
+			g, ok := groups[group]
+			if !ok {
+				g = &assetGroup{Name: group}
+				groups[group] = g
+				groupNames = append(groupNames, group)
+			}
+			g.Files++
+			g.Size += fi.Size()
+			g.CompressedSize += compressed
+			inv.Size += fi.Size()
+			inv.CompressedSize += compressed
+		}
+		slices.SortStableFunc(groupNames, func(a, b string) int {
+			return cmp.Compare(groups[b].CompressedSize, groups[a].CompressedSize)
+		})
+		for _, name := range groupNames {
+			inv.Groups = append(inv.Groups, *groups[name])
+		}
+		inventories = append(inventories, inv)
+	}
+	return inventories, nil
+}
+
+// printAssetInventory 输出资源清单
+func printAssetInventory(w io.Writer, inventories []assetInventory, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent(``, `  `)
+		return enc.Encode(inventories)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for index, inv := range inventories {
+		if index > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "[%s]\n", inv.Key)
+		fmt.Fprintln(tw, "SIZE\tCOMPRESSED\tNAME")
+		for _, file := range inv.Files {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", com.HumaneFileSize(uint64(file.Size)), com.HumaneFileSize(uint64(file.CompressedSize)), file.Name)
+		}
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "SIZE\tCOMPRESSED\tFILES\tGROUP")
+		for _, g := range inv.Groups {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", com.HumaneFileSize(uint64(g.Size)), com.HumaneFileSize(uint64(g.CompressedSize)), g.Files, g.Name)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", com.HumaneFileSize(uint64(inv.Size)), com.HumaneFileSize(uint64(inv.CompressedSize)), len(inv.Files), `(total)`)
+	}
+	return tw.Flush()
+}
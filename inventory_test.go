@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildAssetInventory(t *testing.T) {
+	original := p.Clone()
+	defer func() {
+		p = original
+	}()
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`template/index.html`: strings.Repeat(`index`, 100),
+		`template/index.less`: `ignored`,
+		`vendor/github.com/nging-plugins/collector/template/collector/index.html`: `collector`,
+		`vendor/github.com/nging-plugins/collector/public/assets/collector.js`:    `js`,
+	})
+	p.ProjectPath = root
+	p.AssetDirs = nil
+	p.AutoDiscoveryMiscDir = false
+	p.BindataIgnore = nil
+	p.BindataLevel = gzip.BestCompression
+	inventories, err := buildAssetInventory(context.Background(), map[string][]string{
+		`*`:      {`vendor/github.com/nging-plugins/collector/template/`},
+		`linux`:  {`vendor/github.com/nging-plugins/collector/public/assets/`},
+		`!linux`: {},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, inventories, 2)
+	assert.Equal(t, `!linux`, inventories[0].Key)
+	assert.Len(t, inventories[0].Files, 2)
+
+	linux := inventories[1]
+	assert.Equal(t, `linux`, linux.Key)
+	assert.Len(t, linux.Files, 3)
+	assert.Equal(t, `template/collector/index.html`, linux.Files[0].Name)
+	assert.Equal(t, `vendor/github.com/nging-plugins/collector/`, linux.Files[0].Group)
+	assert.Equal(t, `template/index.html`, linux.Files[2].Name)
+	assert.Equal(t, projectAssetGroup, linux.Files[2].Group)
+	assert.Equal(t, int64(500), linux.Files[2].Size)
+	assert.Less(t, linux.Files[2].CompressedSize, int64(100))
+
+	// 与 go-bindata 的参数一致: 不在 1-9 之间时不传入 -compresslevel，采用默认级别
+	assert.Equal(t, gzip.BestSpeed, bindataCompressLevel(gzip.BestSpeed))
+	for _, level := range []int{0, 12} {
+		assert.Equal(t, gzip.DefaultCompression, bindataCompressLevel(level))
+		p.BindataLevel = level
+		assert.NotContains(t, genComment(context.Background(), nil), `-compresslevel`)
+	}
+
+	assert.Len(t, linux.Groups, 2)
+	groupFiles := map[string]int{}
+	var total int64
+	for _, g := range linux.Groups {
+		groupFiles[g.Name] = g.Files
+		total += g.Size
+	}
+	assert.Equal(t, map[string]int{
+		`vendor/github.com/nging-plugins/collector/`: 2,
+		projectAssetGroup: 1,
+	}, groupFiles)
+	assert.Equal(t, linux.Size, total)
+	assert.Equal(t, int64(511), linux.Size)
+
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, printAssetInventory(buf, inventories, true))
+	var decoded []assetInventory
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, inventories, decoded)
+
+	buf.Reset()
+	assert.NoError(t, printAssetInventory(buf, inventories, false))
+	assert.Contains(t, buf.String(), `[linux]`)
+	assert.Contains(t, buf.String(), `(total)`)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 锁已被其它进程持有
+var errLocked = errors.New(`locked by another process`)
+
+const lockRetryInterval = 500 * time.Millisecond
+
+// 默认等待锁的时间(秒)。写入共享文件时只短暂持有锁，因此默认等待而不是立即失败
+const defaultLockTimeout = 120
+
+// dirLock 打包目录的咨询锁(文件锁)，防止多个构建进程同时写入打包目录中的共享文件。进程退出时自动释放
+type dirLock struct {
+	f *os.File
+}
+
+// lockFileOf 锁文件与目录同级，例如: dist/packed/v5.2.6.lock
+func lockFileOf(dir string) string {
+	return filepath.Clean(dir) + `.lock`
+}
+
+// lockDir 锁定目录。目录已被其它构建进程锁定时等待 wait 秒后失败，wait 为 0 时立即失败，小于 0 时一直等待
+func lockDir(ctx context.Context, dir string, wait int) (*dirLock, error) {
+	file := lockFileOf(dir)
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(time.Duration(wait) * time.Second)
+	var waiting bool
+	for {
+		err = lockFile(f)
+		if err == nil {
+			return &dirLock{f: f}, nil
+		}
+		if !errors.Is(err, errLocked) {
+			f.Close()
+			return nil, err
+		}
+		if wait == 0 || (wait > 0 && time.Now().After(deadline)) {
+			f.Close()
+			return nil, fmt.Errorf(`%s is being written by another nging-builder process (lock file: %s), set LockTimeout to wait for it`, dir, file)
+		}
+		if !waiting {
+			fmt.Println(`Lock		:	 waiting for another nging-builder process to release`, file)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, context.Cause(ctx)
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// withDirLock 在持有目录锁期间执行 fn
+func withDirLock(ctx context.Context, dir string, wait int, fn func() error) error {
+	lock, err := lockDir(ctx, dir, wait)
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+	return fn()
+}
+
+// lockTarget 锁定目标的输出目录。多个构建进程同时构建同一目标时会删除并重建同一个 ReleaseDir，
+// 因此从清理 ReleaseDir 到打包完成都需持有该锁，不同目标仍可并发构建。
+// 单文件模式下各目标共用 distPath，因此按目标名称加锁而不是锁定 ReleaseDir
+func lockTarget(ctx context.Context, distPath string, name string, wait int) (*dirLock, error) {
+	return lockDir(ctx, filepath.Join(distPath, name), wait)
+}
+
+func (l *dirLock) unlock() error {
+	unlockFile(l.f)
+	return l.f.Close()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockDir(t *testing.T) {
+	if runtime.GOOS != `linux` && runtime.GOOS != `darwin` && runtime.GOOS != `windows` {
+		t.Skip(`file locking is not tested on ` + runtime.GOOS)
+	}
+	dir := filepath.Join(t.TempDir(), `v1.0.0`)
+	ctx := context.Background()
+	lock, err := lockDir(ctx, dir, 0)
+	assert.NoError(t, err)
+	assert.FileExists(t, dir+`.lock`)
+
+	_, err = lockDir(ctx, dir, 0)
+	assert.ErrorContains(t, err, `is being written by another nging-builder process`)
+
+	// 等待超时
+	start := time.Now()
+	_, err = lockDir(ctx, dir, 1)
+	assert.Error(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+
+	// 等待期间被中断
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = lockDir(cancelCtx, dir, -1)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// 等待锁释放
+	first := lock
+	unlocked := make(chan error, 1)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		unlocked <- first.unlock()
+	}()
+	lock, err = lockDir(ctx, dir, -1)
+	assert.NoError(t, err)
+	assert.NoError(t, <-unlocked)
+	assert.NoError(t, lock.unlock())
+
+	// 只在执行 fn 期间持有锁
+	err = withDirLock(ctx, dir, 0, func() error {
+		_, err := lockDir(ctx, dir, 0)
+		return err
+	})
+	assert.ErrorContains(t, err, `is being written by another nging-builder process`)
+	assert.NoError(t, withDirLock(ctx, dir, 0, func() error { return nil }))
+}
+
+func TestLockTarget(t *testing.T) {
+	if runtime.GOOS != `linux` && runtime.GOOS != `darwin` && runtime.GOOS != `windows` {
+		t.Skip(`file locking is not tested on ` + runtime.GOOS)
+	}
+	distPath := t.TempDir()
+	ctx := context.Background()
+	lock, err := lockTarget(ctx, distPath, `nging_linux_amd64`, 0)
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(distPath, `nging_linux_amd64.lock`))
+
+	// 同一目标不能同时构建
+	_, err = lockTarget(ctx, distPath, `nging_linux_amd64`, 0)
+	assert.ErrorContains(t, err, `is being written by another nging-builder process`)
+
+	// 不同目标可以并发构建
+	other, err := lockTarget(ctx, distPath, `nging_linux_arm64`, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, other.unlock())
+	assert.NoError(t, lock.unlock())
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -12,7 +13,6 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"slices"
 	"strings"
 	"time"
 
@@ -35,53 +35,32 @@ var c = Config{
 	NgingLabel:   `stable`,
 	Project:      `github.com/admpub/nging`,
 	VendorMiscDirs: map[string][]string{
-		`*`: {
-			`vendor/github.com/nging-plugins/caddymanager/template/`,
-			`vendor/github.com/nging-plugins/caddymanager/public/assets/`,
-			`vendor/github.com/nging-plugins/collector/template/`,
-			`vendor/github.com/nging-plugins/collector/public/assets/`,
-			`vendor/github.com/nging-plugins/dbmanager/template/`,
-			`vendor/github.com/nging-plugins/dbmanager/public/assets/`,
-			`vendor/github.com/nging-plugins/ddnsmanager/template/`,
-			`vendor/github.com/nging-plugins/dlmanager/template/`,
-			`vendor/github.com/nging-plugins/frpmanager/template/`,
-			`vendor/github.com/nging-plugins/ftpmanager/template/`,
-			`vendor/github.com/nging-plugins/servermanager/template/`,
-			`vendor/github.com/nging-plugins/sshmanager/template/`,
-			`vendor/github.com/nging-plugins/webauthn/template/`,
-		},
-		`linux`: {
-			`vendor/github.com/nging-plugins/firewallmanager/template/`,
-		},
+		`*`:      {},
+		`linux`:  {},
 		`!linux`: {},
 	},
-	BuildTags:     []string{`bindata`, `db_sqlite`, `sqlitecgo`},
-	CopyFiles:     []string{`config/ua.txt`, `config/config.yaml.sample`, `data/ip2region`, `config/preupgrade.*`},
-	MakeDirs:      []string{`public/upload`, `config/vhosts`, `data/logs`},
-	BindataIgnore: []string{`[\\/]combined([\\/].*)?$`},
-	Compiler:      `xgo`,
-	BindataLevel:  gzip.BestCompression,
-	CompressLevel: gzip.BestCompression,
-}
-
-var targetNames = map[string]string{
-	`linux_386`:     `linux/386`,
-	`linux_amd64`:   `linux/amd64`,
-	`linux_arm5`:    `linux/arm-5`,
-	`linux_arm6`:    `linux/arm-6`,
-	`linux_arm7`:    `linux/arm-7`,
-	`linux_arm64`:   `linux/arm64`,
-	`darwin_amd64`:  `darwin/amd64`,
-	`darwin_arm64`:  `darwin/arm64`,
-	`windows_386`:   `windows/386`,
-	`windows_amd64`: `windows/amd64`,
-	//`freebsd_amd64`: `freebsd/amd64`, // xgo 不支持
-}
-
-var (
-	xgoSupportedPlatforms    = []string{`darwin`, `linux`, `windows`}
-	xgoSupportedAchitectures = []string{`386`, `amd64`, `arm-5`, `arm-6`, `arm-7`, `arm64`, `mips`, `mipsle`, `mips64`, `mips64le`}
-)
+	PluginModulePrefixes: defaultPluginModulePrefixes,
+	AssetDirs:            defaultAssetDirs,
+	BuildTags:            []string{`bindata`, `db_sqlite`, `sqlitecgo`},
+	CopyFiles:            []string{`config/ua.txt`, `config/config.yaml.sample`, `data/ip2region`, `config/preupgrade.*`},
+	MakeDirs:             []string{`public/upload`, `config/vhosts`, `data/logs`},
+	BindataIgnore:        []string{`[\\/]combined([\\/].*)?$`},
+	TargetGroups: map[string][]string{
+		`server`: {`linux/amd64,linux/arm64`},
+	},
+	Compiler:       `xgo`,
+	CompilerChains: defaultCompilerChains,
+	CompilerRules:  defaultCompilerRules,
+	Bindata:        defaultBindataConfig,
+	I18n:           defaultI18nConfig,
+	TemplateCheck:  defaultTemplateCheckConfig,
+	BindataLevel:   gzip.BestCompression,
+	CompressLevel:  gzip.BestCompression,
+	LockTimeout:    defaultLockTimeout,
+}
+
+// 目标别名。key: 别名(例如: linux_arm7); value: ${GOOS}/${GOARCH}(例如: linux/arm-7)。由目标注册表和配置文件中的 Targets 填充
+var targetNames = map[string]string{}
 
 var armRegexp = regexp.MustCompile(`/arm`)
 var configFile = `./builder.conf`
@@ -92,6 +71,9 @@ var releaseVersion string
 var goVersion string
 var compiler string
 var combineChecksum bool = true
+var checkGenComment bool
+var assetsJSON bool
+var noCache bool
 
 func main() {
 	flag.StringVar(&configFile, `conf`, configFile, `--conf `+configFile)
@@ -99,14 +81,20 @@ func main() {
 	flag.BoolVar(&showVersion, `version`, false, `--version`)
 	flag.StringVar(&outputDir, `outputDir`, outputDir, `--outputDir ./dist`)
 	flag.StringVar(&releaseVersion, `releaseVersion`, releaseVersion, `--releaseVersion 3.1.1`)
-	flag.StringVar(&compiler, `compiler`, compiler, `--compiler go or --compiler xgo`)
+	flag.StringVar(&compiler, `compiler`, compiler, `--compiler go or --compiler xgo or --compiler auto`)
 	flag.StringVar(&goVersion, `goVersion`, goVersion, `--goVersion 1.24.4`)
 	flag.BoolVar(&combineChecksum, `combineChecksum`, combineChecksum, `--combineChecksum true`)
+	flag.BoolVar(&noCache, `no-cache`, noCache, `--no-cache`)
 	defaultUsage := flag.Usage
 	flag.Usage = func() {
 		defaultUsage()
 		fmt.Println()
 		fmt.Println(`Command Format:`, os.Args[0], `[os_arch]`, `[min]`)
+		fmt.Println(`Target Format :`, `linux_amd64,darwin/arm64,linux/amd64-v3,linux/*,*/arm64,!windows_386,host,go:groupName`)
+		fmt.Println(`List Targets  :`, os.Args[0], `list-targets`)
+		fmt.Println(`List Misc Dirs:`, os.Args[0], `list-misc-dirs`)
+		fmt.Println(`Asset Report  :`, os.Args[0], `assets [--json]`)
+		fmt.Println(`Check go:generate files:`, os.Args[0], `genComment --check`)
 	}
 	flag.Parse()
 
@@ -114,11 +102,12 @@ func main() {
 		fmt.Println(version)
 		return
 	}
-	isGenConfig := len(flag.Args()) == 1 && com.InSlice(`genConfig`, flag.Args())
+	isGenConfig := len(flag.Args()) == 1 && (com.InSlice(`genConfig`, flag.Args()) || com.InSlice(`list-targets`, flag.Args()))
 
 	configInFile := Config{
 		BindataLevel:  gzip.BestCompression,
 		CompressLevel: gzip.BestCompression,
+		LockTimeout:   defaultLockTimeout,
 	}
 	_, err := confl.DecodeFile(configFile, &configInFile)
 	if err != nil && !isGenConfig {
@@ -145,24 +134,7 @@ func main() {
 	var targets []string
 	var armTargets []string
 	targetCompilers := map[string]string{}
-	addTarget := func(target string, notNames ...bool) {
-		parts := strings.SplitN(target, `:`, 2)
-		for k, v := range parts {
-			parts[k] = strings.TrimSpace(v)
-		}
-		var compiler string
-		if len(parts) == 2 {
-			target = parts[1]
-			compiler = parts[0]
-		} else {
-			target = parts[0]
-		}
-		if len(notNames) == 0 || !notNames[0] {
-			target = getTarget(target)
-			if len(target) == 0 {
-				return
-			}
-		}
+	addTarget := func(target string, compiler string) {
 		if armRegexp.MatchString(target) {
 			armTargets = append(armTargets, target)
 		} else {
@@ -172,29 +144,48 @@ func main() {
 			targetCompilers[target] = compiler
 		}
 	}
+	addTargets := func(expr string) {
+		selected, err := parseTargetExpr(expr)
+		if err != nil {
+			com.ExitOnFailure(`Error		:	 `+err.Error()+"\n", 1)
+		}
+		for _, v := range selected {
+			addTarget(v.Target, v.Compiler)
+		}
+	}
 	args := make([]string, len(flag.Args()))
 	copy(args, flag.Args())
 	var minify bool
 	var target string
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := signalContext()
 	defer cancel()
+	if len(args) > 1 && (args[0] == `genComment` || args[0] == `makeGen`) {
+		fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+		fs.BoolVar(&checkGenComment, `check`, checkGenComment, `--check`)
+		fs.Parse(args[1:])
+		args = args[:1]
+	}
+	if len(args) > 1 && args[0] == `assets` {
+		fs := flag.NewFlagSet(args[0], flag.ExitOnError)
+		fs.BoolVar(&assetsJSON, `json`, assetsJSON, `--json`)
+		fs.Parse(args[1:])
+		args = args[:1]
+	}
 	switch len(args) {
 	case 2:
 		minify = isMinified(args[1])
 		target = args[0]
-		for _, _target := range strings.Split(target, `,`) {
-			_target = strings.TrimSpace(_target)
-			if len(_target) == 0 {
-				continue
-			}
-			addTarget(_target)
-		}
+		addTargets(target)
 	case 1:
 		switch {
 		case isMinified(args[0]):
 			minify = true
-			for _, t := range targetNames {
-				addTarget(t, true)
+			defaults, err := getDefaultTargets()
+			if err != nil {
+				com.ExitOnFailure(err.Error(), 1)
+			}
+			for _, t := range defaults {
+				addTarget(t, ``)
 			}
 		case args[0] == `genConfig`:
 			b, err := confl.Marshal(c)
@@ -210,7 +201,13 @@ func main() {
 		case args[0] == `genComment`:
 			fallthrough
 		case args[0] == `makeGen`:
-			makeGenerateCommandComment()
+			if checkGenComment {
+				if !checkGenerateCommandComment(ctx, os.Stdout) {
+					com.ExitOnFailure(`generated files are out of date, run "genComment" to update them`+"\n", 1)
+				}
+				return
+			}
+			makeGenerateCommandComment(ctx)
 			return
 		case args[0] == `genChecksums`:
 			_, packedDir := getDistPathAndPackedDir(ctx)
@@ -222,7 +219,20 @@ func main() {
 			if err != nil {
 				com.ExitOnFailure(err.Error(), 1)
 			}
-			err = makeChecksums(files, packedDir)
+			algos, err := checksumAlgorithms(p.ChecksumAlgorithms)
+			if err != nil {
+				com.ExitOnFailure(err.Error(), 1)
+			}
+			checksums := make([]fileChecksum, len(files))
+			for index, file := range files {
+				checksums[index], err = checksumFile(file, algos)
+				if err != nil {
+					com.ExitOnFailure(err.Error(), 1)
+				}
+			}
+			err = withDirLock(ctx, packedDir, p.LockTimeout, func() error {
+				return makeChecksums(checksums, packedDir, algos)
+			})
 			if err != nil {
 				com.ExitOnFailure(err.Error(), 1)
 			}
@@ -231,25 +241,45 @@ func main() {
 		case args[0] == `version`:
 			fmt.Println(version)
 			return
+		case args[0] == `assets`:
+			inventories, err := buildAssetInventory(ctx, vendorMiscDirsWithPlugins(ctx))
+			if err != nil {
+				com.ExitOnFailure(err.Error(), 1)
+			}
+			err = printAssetInventory(os.Stdout, inventories, assetsJSON)
+			if err != nil {
+				com.ExitOnFailure(err.Error(), 1)
+			}
+			return
+		case args[0] == `list-misc-dirs`:
+			listMiscDirs(ctx, os.Stdout)
+			return
+		case args[0] == `list-targets`:
+			err = listTargets(os.Stdout)
+			if err != nil {
+				com.ExitOnFailure(err.Error(), 1)
+			}
+			return
 		default:
 			target = args[0]
-			for _, _target := range strings.Split(target, `,`) {
-				_target = strings.TrimSpace(_target)
-				if len(_target) == 0 {
-					continue
-				}
-				addTarget(_target)
-			}
+			addTargets(target)
 		}
 	case 0:
-		for _, t := range targetNames {
-			addTarget(t, true)
+		defaults, err := getDefaultTargets()
+		if err != nil {
+			com.ExitOnFailure(err.Error(), 1)
+		}
+		for _, t := range defaults {
+			addTarget(t, ``)
 		}
 	default:
 		com.ExitOnFailure(`invalid parameter`)
 	}
+	var vendorMiscDirs map[string][]string
 	if !noMisc {
-		makeGenerateCommandComment()
+		vendorMiscDirs = makeGenerateCommandComment(ctx)
+	} else {
+		vendorMiscDirs = vendorMiscDirsWithPlugins(ctx)
 	}
 	fmt.Println(`ConfFile	:	`, configFile)
 	fmt.Println(`WorkDir		:	`, p.WorkDir)
@@ -271,12 +301,24 @@ func main() {
 
 	fmt.Printf("Building %s for %+v\n", p.Executor, allTargets)
 	singleFileMode := isSingleFile()
-	var compressedFiles []string
+	algos, err := checksumAlgorithms(p.ChecksumAlgorithms)
+	if err != nil {
+		com.ExitOnFailure(err.Error(), 1)
+	}
+	var cacheDir string
+	if !noCache {
+		cacheDir = p.cacheDir()
+	}
+	genCache := newGenerateCache(cacheDir, vendorMiscDirs)
+	binCache := newBuildCache(cacheDir, genCache, distPath)
+	binCache.remote = newRemoteCache(p.RemoteCache)
+	var checksums []fileChecksum
 	for _, target := range allTargets {
 		parts := strings.SplitN(target, `/`, 2)
 		if len(parts) != 2 {
 			continue
 		}
+		exitIfCanceled(ctx)
 		pCopy := p.Clone()
 		if len(compiler) > 0 {
 			pCopy.Compiler = compiler
@@ -286,16 +328,26 @@ func main() {
 			}
 		}
 		pCopy.Target = target
-		if !com.InSlice(`osusergo`, pCopy.PureGoTags) {
-			pCopy.PureGoTags = append(pCopy.PureGoTags, `osusergo`)
-		}
 		osName := parts[0]
 		archName := parts[1]
+		pCopy.PureGoTags = targetPureGoTags(pCopy.PureGoTags, osName)
+		targetName := p.Executor + `_` + osName + `_` + archName
+		targetLock, err := lockTarget(ctx, distPath, targetName, p.LockTimeout)
+		if err != nil {
+			exitIfCanceled(ctx)
+			com.ExitOnFailure(err.Error(), 1)
+		}
 		if singleFileMode {
 			pCopy.ReleaseDir = distPath
+			partial.begin(pCopy.ReleaseDir, true)
 		} else {
-			pCopy.ReleaseDir = filepath.Join(distPath, p.Executor+`_`+osName+`_`+archName)
-			err = com.MkdirAll(pCopy.ReleaseDir, os.ModePerm)
+			pCopy.ReleaseDir = filepath.Join(distPath, targetName)
+			partial.begin(pCopy.ReleaseDir, false)
+			// 删除上次中断的构建残留的文件
+			err = os.RemoveAll(pCopy.ReleaseDir)
+			if err == nil {
+				err = com.MkdirAll(pCopy.ReleaseDir, os.ModePerm)
+			}
 			if err != nil {
 				com.ExitOnFailure(err.Error(), 1)
 			}
@@ -303,16 +355,10 @@ func main() {
 		pCopy.goos = osName
 		pCopy.goarch = archName
 
-		// xgo 不支持的时候，采用纯 go 版 sqlite
-		if pCopy.Compiler == `xgo` && (!com.InSlice(osName, xgoSupportedPlatforms) || !com.InSlice(archName, xgoSupportedAchitectures)) {
-			pCopy.Compiler = `go`
-		}
-		if pCopy.Compiler == `go` {
-			if com.InSlice(`sqlitecgo`, pCopy.BuildTags) {
-				pCopy.BuildTags = slices.DeleteFunc(pCopy.BuildTags, func(v string) bool {
-					return v == `sqlitecgo`
-				})
-			}
+		// xgo 不支持的时候，按编译器链回退(默认回退到 go 并采用纯 go 版 sqlite)
+		err = pCopy.resolveCompiler()
+		if err != nil {
+			com.ExitOnFailure(err.Error(), 1)
 		}
 		if osName != `darwin` {
 			if !com.InSlice(`-extldflags`, pCopy.LdFlags) {
@@ -322,26 +368,81 @@ func main() {
 				pCopy.LdFlags = append(pCopy.LdFlags, `'-static'`)
 			}
 		}
-		if osName != `windows` {
-			if !com.InSlice(`netgo`, pCopy.PureGoTags) {
-				pCopy.PureGoTags = append(pCopy.PureGoTags, `netgo`)
-			}
-		} else {
+		if osName == `windows` {
 			pCopy.Extension = `.exe`
 		}
-		execGenerateCommand(ctx, pCopy)
-		execBuildCommand(ctx, pCopy)
-		normalizeExecuteFileName(pCopy, singleFileMode)
+		buildKey, err := binCache.key(ctx, pCopy)
+		if err != nil {
+			com.ExitOnFailure(err.Error(), 1)
+		}
+		cached, err := binCache.restore(ctx, buildKey, pCopy.ReleaseDir)
+		if err != nil {
+			exitIfCanceled(ctx)
+			com.ExitOnFailure(err.Error(), 1)
+		}
+		if cached {
+			// 缓存中已包含 Generate 和 Build 阶段钩子的处理结果，因此不再执行这两个阶段的钩子
+			fmt.Println(`Build		:	 reuse cached binary`, buildKey[:12])
+		} else {
+			pCopy.runHooks(ctx, hookBeforeGenerate, ``)
+			runStage(ctx, `generate`, pCopy.Timeouts.Generate, func(ctx context.Context) {
+				err := genCache.run(ctx, pCopy, execGenerateCommand)
+				if err != nil {
+					exitIfCanceled(ctx)
+					com.ExitOnFailure(err.Error(), 1)
+				}
+			})
+			pCopy.runHooks(ctx, hookAfterGenerate, ``)
+			before := snapshotReleaseDir(pCopy.ReleaseDir)
+			pCopy.runHooks(ctx, hookBeforeBuild, ``)
+			runStage(ctx, `build`, pCopy.Timeouts.Build, func(ctx context.Context) {
+				execBuildCommand(ctx, pCopy)
+			})
+			pCopy.runHooks(ctx, hookAfterBuild, ``)
+			files := changedFiles(pCopy.ReleaseDir, before)
+			if singleFileMode {
+				files = targetFiles(files, pCopy.Executor+`-`+osName+`-`+archName)
+			}
+			err = binCache.store(ctx, buildKey, pCopy.ReleaseDir, files)
+			if err != nil {
+				fmt.Println(`Warning		:	 failed to cache binary:`, err)
+			}
+		}
+		normalizeExecuteFileName(pCopy, singleFileMode, algos)
 		if !singleFileMode {
-			compressedFile := packFiles(pCopy, packedDir)
-			compressedFiles = append(compressedFiles, compressedFile)
+			pCopy.runHooks(ctx, hookBeforePack, ``)
+			var checksum fileChecksum
+			runStage(ctx, `pack`, pCopy.Timeouts.Pack, func(ctx context.Context) {
+				checksum = packFiles(ctx, pCopy, packedDir, algos)
+			})
+			compressedFile := checksum.File
+			partial.archive = compressedFile
+			pCopy.runHooks(ctx, hookAfterPack, compressedFile)
+			checksums = append(checksums, checksum)
+			if !combineChecksum {
+				pCopy.runHooks(ctx, hookBeforeChecksums, compressedFile)
+				err = writeChecksumFiles(checksum, algos)
+				if err != nil {
+					com.ExitOnFailure(err.Error(), 1)
+				}
+				pCopy.runHooks(ctx, hookAfterChecksums, compressedFile+`.sha256`)
+			}
 		}
-	}
-	if combineChecksum && len(compressedFiles) > 0 {
-		err = makeChecksums(compressedFiles, packedDir)
+		targetLock.unlock()
+		partial.done()
+	}
+	if combineChecksum && len(checksums) > 0 {
+		// 中断时不生成 checksums.txt
+		exitIfCanceled(ctx)
+		checksumsFile := filepath.Join(packedDir, `checksums.txt`)
+		p.runHooks(ctx, hookBeforeChecksums, checksumsFile)
+		err = withDirLock(ctx, packedDir, p.LockTimeout, func() error {
+			return makeChecksums(checksums, packedDir, algos)
+		})
 		if err != nil {
 			com.ExitOnFailure(err.Error())
 		}
+		p.runHooks(ctx, hookAfterChecksums, checksumsFile)
 	}
 }
 
@@ -379,6 +480,9 @@ func getDistPathAndPackedDir(ctx context.Context) (string, string) {
 }
 
 func getTarget(target string) string {
+	if t, y := lookupTargetInfo(target); y {
+		return t.Target()
+	}
 	if t, y := targetNames[target]; y {
 		return t
 	}
@@ -485,22 +589,31 @@ func (p buildParam) genLdFlagsStringForStartup(version string) string {
 
 func (p buildParam) genEnvVars() []string {
 	env := []string{`GOOS=` + p.goos}
-	parts := strings.SplitN(p.goarch, `-`, 2)
-	if parts[0] == `arm` {
-		env = append(env, `GOARCH=`+parts[0])
-		if len(parts) == 2 {
-			env = append(env, `GOARM=`+parts[1])
-		}
-	} else {
-		env = append(env, `GOARCH=`+p.goarch)
-	}
+	env = append(env, archEnvVars(p.goarch)...)
 	return env
 }
 
-func execBuildCommand(ctx context.Context, p buildParam) {
+// targetPureGoTags 添加目标平台的纯 Go 标签: osusergo，以及 windows 以外的系统的 netgo
+func targetPureGoTags(tags []string, osName string) []string {
+	if !com.InSlice(`osusergo`, tags) {
+		tags = append(tags, `osusergo`)
+	}
+	if osName != `windows` && !com.InSlice(`netgo`, tags) {
+		tags = append(tags, `netgo`)
+	}
+	return tags
+}
+
+// buildTags 返回构建标签(包括纯 Go 标签)
+func (p buildParam) buildTags() []string {
 	tags := make([]string, 0, len(p.PureGoTags)+len(p.BuildTags))
 	tags = append(tags, p.PureGoTags...)
 	tags = append(tags, p.BuildTags...)
+	return tags
+}
+
+func execBuildCommand(ctx context.Context, p buildParam) {
+	tags := p.buildTags()
 	var args []string
 	var env []string
 	var workDir string
@@ -555,14 +668,14 @@ func execBuildCommand(ctx context.Context, p buildParam) {
 			`./` + p.Project,
 		}
 	}
-	cmd := exec.CommandContext(ctx, compiler, args...)
+	cmd := newCommand(ctx, compiler, args...)
 	cmd.Dir = workDir
-	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
 	cmd.Stdout = os.Stdout
 	cmd.Env = env
 	err := cmd.Run()
 	if err != nil {
+		exitIfCanceled(ctx)
 		com.ExitOnFailure(err.Error(), 1)
 	}
 	if len(p.StartupPackage) > 0 {
@@ -589,29 +702,30 @@ func execBuildCommandForStartup(ctx context.Context, p buildParam) {
 		`-ldflags`, p.genLdFlagsStringForStartup(version),
 		`-o`, filepath.Join(p.ReleaseDir, `startup`+p.Extension),
 	}
-	cmd := exec.CommandContext(ctx, compiler, args...)
+	cmd := newCommand(ctx, compiler, args...)
 	cmd.Dir = workDir
-	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
 	cmd.Stdout = os.Stdout
 	cmd.Env = append(cmd.Env, os.Environ()...)
 	cmd.Env = append(cmd.Env, p.genEnvVars()...)
 	err = cmd.Run()
 	if err != nil {
+		exitIfCanceled(ctx)
 		com.ExitOnFailure(err.Error(), 1)
 	}
 }
 
 func execGenerateCommand(ctx context.Context, p buildParam) {
-	cmd := exec.CommandContext(ctx, `go`, `generate`)
+	// 使用与编译相同的标签，仅由标签决定的 VendorMiscDirs key 生成的文件中的 go:generate 指令才会执行
+	cmd := newCommand(ctx, `go`, `generate`, `-tags`, strings.Join(p.buildTags(), ` `))
 	cmd.Dir = p.ProjectPath
-	cmd.Stdin = os.Stdin
 	cmd.Stderr = os.Stderr
 	cmd.Stdout = os.Stdout
 	cmd.Env = append(cmd.Env, os.Environ()...)
 	cmd.Env = append(cmd.Env, p.genEnvVars()...)
 	err := cmd.Run()
 	if err != nil {
+		exitIfCanceled(ctx)
 		com.ExitOnFailure(err.Error(), 1)
 	}
 }
@@ -636,7 +750,7 @@ func execGitCommitVersionCommand(ctx context.Context) string {
 	return strings.TrimPrefix(strings.TrimSpace(string(out)), `v`)
 }
 
-func normalizeExecuteFileName(p buildParam, singleFileMode bool) {
+func normalizeExecuteFileName(p buildParam, singleFileMode bool, algos []string) {
 	if singleFileMode {
 		name := p.Executor + `-` + p.goos + `-` + p.goarch
 		finalName := filepath.Join(p.ReleaseDir, name)
@@ -645,7 +759,7 @@ func normalizeExecuteFileName(p buildParam, singleFileMode bool) {
 			finalName += p.Extension
 			com.Rename(original, finalName)
 		}
-		makeChecksum(finalName)
+		makeBinaryChecksum(finalName, algos)
 		return
 	}
 	files, err := filepath.Glob(filepath.Join(p.ReleaseDir, p.Executor+`-`+p.goos+`*`))
@@ -655,12 +769,23 @@ func normalizeExecuteFileName(p buildParam, singleFileMode bool) {
 	for _, file := range files {
 		finalName := filepath.Join(p.ReleaseDir, p.Executor+p.Extension)
 		com.Rename(file, finalName)
-		makeChecksum(finalName)
+		makeBinaryChecksum(finalName, algos)
 		break
 	}
 }
 
-func packFiles(p buildParam, packedDir string) string {
+// makeBinaryChecksum 读取一次可执行文件生成所有算法的校验和文件
+func makeBinaryChecksum(file string, algos []string) {
+	sum, err := checksumFile(file, algos)
+	if err == nil {
+		err = writeChecksumFiles(sum, algos)
+	}
+	if err != nil {
+		com.ExitOnFailure(err.Error(), 1)
+	}
+}
+
+func packFiles(ctx context.Context, p buildParam, packedDir string, algos []string) fileChecksum {
 	var files []string
 	var err error
 	for _, copyFile := range p.CopyFiles {
@@ -701,86 +826,123 @@ func packFiles(p buildParam, packedDir string) string {
 		}
 	}
 	compressedFile := filepath.Join(packedDir, filepath.Base(p.ReleaseDir)) + `.tar.gz`
+	compressLevel := gzip.DefaultCompression
 	if p.CompressLevel > 0 {
-		compressLevel := p.CompressLevel
-		if compressLevel > gzip.BestCompression {
-			compressLevel = gzip.BestCompression
-		}
-		err = com.TarGzWithLevel(compressLevel, p.ReleaseDir, compressedFile)
-	} else {
-		err = com.TarGz(p.ReleaseDir, compressedFile)
+		compressLevel = min(p.CompressLevel, gzip.BestCompression)
 	}
+	fmt.Println(`Pack		:	`, compressedFile)
+	f, err := createAtomic(compressedFile)
 	if err != nil {
 		com.ExitOnFailure(err.Error(), 1)
 	}
+	checksum, err := tarGz(ctx, f, p.ReleaseDir, compressLevel, p.CompressThreads, algos)
+	if err != nil {
+		f.abort()
+		exitIfCanceled(ctx)
+		com.ExitOnFailure(err.Error(), 1)
+	}
+	checksum.File = compressedFile
+	// 只在重命名压缩包时锁定打包目录
+	err = withDirLock(ctx, packedDir, p.LockTimeout, f.commit)
+	if err != nil {
+		f.abort()
+		exitIfCanceled(ctx)
+		com.ExitOnFailure(err.Error(), 1)
+	}
 	err = os.RemoveAll(p.ReleaseDir)
 	if err != nil {
 		com.ExitOnFailure(err.Error(), 1)
 	}
 	// 解压: tar -zxvf nging_linux_amd64.tar.gz -C ./nging_linux_amd64
-
-	if !combineChecksum {
-		err = makeChecksum(compressedFile)
-		if err != nil {
-			com.ExitOnFailure(err.Error(), 1)
-		}
-	}
-	return compressedFile
+	return checksum
 }
 
-func genComment(bindataIgnore []string, vendorMiscDirs ...string) string {
-	comment := "//go:generate go install github.com/admpub/bindata/v3/go-bindata@latest\n"
-	comment += `//go:generate go-bindata -fs -o bindata_assetfs.go`
-	if p.BindataLevel > 0 && gzip.BestCompression >= p.BindataLevel {
+func genComment(ctx context.Context, bindataIgnore []string, vendorMiscDirs ...string) string {
+	g := p.Bindata.WithDefaults()
+	comment := "//go:generate go install " + g.Module + `@` + g.Version + "\n"
+	comment += `//go:generate ` + g.Command() + ` -fs -o ` + quoteGenerateArg(g.Output)
+	if isBindataLevelSet(p.BindataLevel) {
 		comment += fmt.Sprintf(` -compresslevel %d`, p.BindataLevel)
 	}
 	for _, v := range bindataIgnore {
 		comment += fmt.Sprintf(" -ignore %q", v)
 	}
-	comment += ` -ignore "\\.(git|svn|DS_Store|less|scss|gitkeep|go)$" -minify "\\.(js|css)$" -tags bindata`
-	miscDirs := []string{}
-	miscDirs = append(miscDirs, vendorMiscDirs...)
-	miscDirs = append(miscDirs,
-		`public/assets/`,
-		`template/`,
-		`config/i18n/`,
-	)
-	var prefixes []string
-	prefixes, miscDirs = buildGoGenerateCommandData(miscDirs)
+	comment += fmt.Sprintf(" -ignore %q", g.Ignore)
+	if len(g.Minify) > 0 {
+		comment += fmt.Sprintf(" -minify %q", g.Minify)
+	}
+	comment += ` -tags ` + quoteGenerateArg(g.Tags)
+	for _, v := range g.ExtraFlags {
+		comment += ` ` + quoteGenerateArg(v)
+	}
+	prefixes, miscDirs := genMiscDirs(ctx, vendorMiscDirs...)
 	comment += ` -prefix "` + strings.Join(prefixes, `|`) + `" `
 	comment += strings.Join(miscDirs, ` `)
 	return comment
 }
 
-var templateAndPublicMisc = regexp.MustCompile(`(/|^)(template|public/assets|config/i18n)(/|/[.]{3})?$`)
+// isBindataLevelSet 压缩级别在 1-9 之间时才传入 -compresslevel 参数，否则采用 go-bindata 的默认压缩级别
+func isBindataLevelSet(level int) bool {
+	return level > 0 && gzip.BestCompression >= level
+}
+
+// bindataCompressLevel 返回 go-bindata 实际采用的压缩级别
+func bindataCompressLevel(level int) int {
+	if isBindataLevelSet(level) {
+		return level
+	}
+	return gzip.DefaultCompression
+}
+
+// 资源生成工具默认忽略的文件
+const defaultBindataIgnore = `\.(git|svn|DS_Store|less|scss|gitkeep|go)$`
+
+// genMiscDirs 返回需要打包的所有资源目录(包括项目自身的资源目录)及需要去除的前缀
+func genMiscDirs(ctx context.Context, vendorMiscDirs ...string) (prefixes []string, miscDirs []string) {
+	defaultMiscDirs := projectMiscDirs()
+	miscDirs = make([]string, 0, len(vendorMiscDirs)+len(defaultMiscDirs))
+	miscDirs = append(miscDirs, vendorMiscDirs...)
+	miscDirs = append(miscDirs, defaultMiscDirs...)
+	return buildGoGenerateCommandData(ctx, miscDirs)
+}
+
+// 默认资源目录约定对应的正则表达式
+var templateAndPublicMisc = assetDirRegexp(defaultAssetDirs)
 
-func buildGoGenerateCommandData(miscDirs []string) (prefixes []string, miscDirsNew []string) {
+func buildGoGenerateCommandData(ctx context.Context, miscDirs []string) (prefixes []string, miscDirsNew []string) {
 	uniquePrefixes := map[string]struct{}{}
+	dirs := assetDirs()
+	assetDirMisc := templateAndPublicMisc
+	if len(p.AssetDirs) > 0 {
+		assetDirMisc = assetDirRegexp(dirs)
+	}
 	autoDiscovery := func(dir string) bool {
-		if !p.AutoDiscoveryMiscDir || templateAndPublicMisc.MatchString(dir) {
+		if !p.AutoDiscoveryMiscDir || assetDirMisc.MatchString(dir) {
 			return false
 		}
-		dirNew := templateAndPublicMisc.ReplaceAllString(dir, ``)
-		tmplDir := filepath.Join(dirNew, `template`)
+		dirNew := assetDirMisc.ReplaceAllString(dir, ``)
 		var isMod bool
-		if com.IsDir(tmplDir) {
-			miscDirsNew = append(miscDirsNew, tmplDir+`/...`)
-			isMod = true
-		}
-		pubDir := filepath.Join(dirNew, `public/assets`)
-		if com.IsDir(pubDir) {
-			miscDirsNew = append(miscDirsNew, pubDir+`/...`)
-			isMod = true
-		}
-		i18nDir := filepath.Join(dirNew, `config/i18n`)
-		if com.IsDir(i18nDir) {
-			miscDirsNew = append(miscDirsNew, i18nDir+`/...`)
-			isMod = true
+		for _, assetDir := range dirs {
+			subDir := filepath.Join(dirNew, assetDir)
+			if com.IsDir(subDir) {
+				miscDirsNew = append(miscDirsNew, subDir+`/...`)
+				isMod = true
+			}
 		}
 		return isMod
 	}
 	for _, v := range miscDirs {
-		if strings.HasPrefix(v, `vendor/`) {
+		if isModuleMiscDir(v) { // `github.com/nging-plugins/collector@/template/`
+			dir, prefix, err := resolveModuleMiscDir(ctx, p.ProjectPath, v)
+			if err != nil {
+				com.ExitOnFailure(err.Error(), 1)
+			}
+			if _, ok := uniquePrefixes[prefix]; !ok {
+				uniquePrefixes[prefix] = struct{}{}
+				prefixes = append(prefixes, prefix)
+			}
+			v = dir
+		} else if strings.HasPrefix(v, `vendor/`) {
 			parts := strings.SplitN(v, `/`, 5)
 			if len(parts) == 5 { // `vendor/github.com/nging-plugins/collector/template/`  `vendor/github.com/nging-plugins/collector/public/`
 				prefix := strings.Join(parts[0:4], `/`) + `/`
@@ -820,42 +982,125 @@ func buildGoGenerateCommandData(miscDirs []string) (prefixes []string, miscDirsN
 	return
 }
 
-func makeGenerateCommandComment() {
-	dfts := p.VendorMiscDirs[`*`]
-	for osName, miscDirs := range p.VendorMiscDirs {
-		if osName == `*` {
-			continue
-		}
-		dirs := make([]string, 0, len(dfts)+len(miscDirs))
-		dirs = append(dirs, dfts...)
-		dirs = append(dirs, miscDirs...)
-		fileName := `main_`
-		if strings.HasPrefix(osName, `!`) {
-			fileName += `non` + strings.TrimPrefix(osName, `!`)
-		} else {
-			fileName += osName
+// generatedFile 生成的包含 go:generate 指令的文件
+type generatedFile struct {
+	Path    string
+	Content string
+	OSName  string      // VendorMiscDirs 的 key
+	Assets  []assetFile // embed 模式下需要复制的资源文件
+}
+
+// vendorMiscDirsWithPlugins 返回合并了自动发现的插件资源目录后的 VendorMiscDirs
+func vendorMiscDirsWithPlugins(ctx context.Context) map[string][]string {
+	configuredMiscDirs, err := configuredPluginMiscDirs(ctx)
+	if err != nil {
+		com.ExitOnFailure(err.Error(), 1)
+	}
+	pluginMiscDirs, err := discoverPluginMiscDirs(ctx)
+	if err != nil {
+		com.ExitOnFailure(err.Error(), 1)
+	}
+	return mergeMiscDirs(mergeMiscDirs(p.VendorMiscDirs, configuredMiscDirs), pluginMiscDirs)
+}
+
+// buildGenerateCommandFiles 生成各个 main_<os>.go 文件的内容
+func buildGenerateCommandFiles(ctx context.Context, vendorMiscDirs map[string][]string) ([]generatedFile, error) {
+	osNames := miscDirKeys(vendorMiscDirs)
+	if err := checkMiscDirKeys(osNames, p.buildTags()); err != nil {
+		return nil, err
+	}
+	files := make([]generatedFile, 0, len(osNames))
+	suffixes := map[string]string{}
+	for _, osName := range osNames {
+		suffix := miscDirKeyFileSuffix(osName)
+		if other, ok := suffixes[suffix]; ok {
+			return nil, fmt.Errorf(`VendorMiscDirs keys %q and %q generate the same file name: main_%s.go`, other, osName, suffix)
 		}
-		fileName += `.go`
+		suffixes[suffix] = osName
+		dirs := miscDirsOfKey(vendorMiscDirs, osName)
+		fileName := `main_` + suffix + `.go`
 		filePath := filepath.Join(p.ProjectPath, fileName)
-		fileContent := "//go:build " + osName + "\n\n"
-		fileContent += "package main\n\n"
-		fileContent += genComment(p.BindataIgnore, dirs...) + "\n\n"
-		fmt.Println(`[go:generate]	:	`, filePath)
 		b, err := os.ReadFile(filePath)
-		if err == nil {
-			old := string(b)
-			pos := strings.Index(old, `import `)
-			if pos > -1 {
-				fileContent += old[pos:]
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		prefixes, assetMiscDirs := genMiscDirs(ctx, dirs...)
+		assets, err := collectAssetFiles(p.ProjectPath, prefixes, assetMiscDirs, p.BindataIgnore)
+		if err != nil {
+			return nil, err
+		}
+		if collisions := findAssetCollisions(assets, p.AllowAssetCollisions); len(collisions) > 0 {
+			return nil, assetCollisionError(osName, collisions)
+		}
+		var directives []string
+		if p.AssetGenerator == assetGeneratorEmbed {
+			files = append(files, generatedFile{
+				Path:    filepath.Join(p.ProjectPath, embedGoFileName(suffix)),
+				Content: embedGoSource(osName, len(assets) > 0),
+				OSName:  osName,
+				Assets:  assets,
+			})
+		} else {
+			directives = strings.Split(genComment(ctx, p.BindataIgnore, dirs...), "\n")
+		}
+		b, err = rewriteGenerateFile(b, normalizeMiscDirKey(osName), directives)
+		if err != nil {
+			return nil, fmt.Errorf(`%s: %w`, filePath, err)
+		}
+		files = append(files, generatedFile{Path: filePath, Content: string(b)})
+	}
+	pluginFiles, err := buildPluginImportFiles(p.Plugins)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, pluginFiles...)
+	return files, nil
+}
+
+func makeGenerateCommandComment(ctx context.Context) map[string][]string {
+	vendorMiscDirs := vendorMiscDirsWithPlugins(ctx)
+	if p.I18n.Validate {
+		valid, err := checkI18nMessages(ctx, vendorMiscDirs, os.Stdout)
+		if err != nil {
+			com.ExitOnFailure(err.Error(), 1)
+		}
+		if !valid && p.I18n.Strict {
+			com.ExitOnFailure(`i18n message files are invalid`+"\n", 1)
+		}
+	}
+	if p.TemplateCheck.Validate {
+		valid, err := checkTemplates(ctx, vendorMiscDirs, os.Stdout)
+		if err != nil {
+			com.ExitOnFailure(err.Error(), 1)
+		}
+		if !valid && p.TemplateCheck.Strict {
+			com.ExitOnFailure(`template files have syntax errors`+"\n", 1)
+		}
+	}
+	files, err := buildGenerateCommandFiles(ctx, vendorMiscDirs)
+	if err != nil {
+		com.ExitOnFailure(err.Error(), 1)
+	}
+	removeStalePluginImportFiles(files)
+	for _, file := range files {
+		if isPluginImportFile(file.Path) {
+			fmt.Println(`[plugins]	:	`, file.Path)
+		} else if p.AssetGenerator == assetGeneratorEmbed && isEmbedGoFile(file.Path) {
+			// 没有资源文件时也要清空 embed 目录中上次复制的资源
+			fmt.Println(`[go:embed]	:	`, file.Path)
+			err = stageEmbedAssets(p.ProjectPath, file.OSName, file.Assets)
+			if err != nil {
+				com.ExitOnFailure(err.Error(), 1)
 			}
 		} else {
-			fmt.Println(err)
+			fmt.Println(`[go:generate]	:	`, file.Path)
 		}
-		err = os.WriteFile(filePath, []byte(fileContent), os.ModePerm)
+		err := os.WriteFile(file.Path, []byte(file.Content), os.ModePerm)
 		if err != nil {
 			fmt.Println(err.Error())
 		}
 	}
+	return vendorMiscDirs
 }
 
 type Config struct {
@@ -868,17 +1113,37 @@ type Config struct {
 	NgingPackage         string
 	StartupPackage       string
 	Project              string
-	VendorMiscDirs       map[string][]string // key: GOOS
+	VendorMiscDirs       map[string][]string // key: `*` 或构建约束表达式(例如 linux、!linux、linux && (amd64 || arm64)); value: vendor/ 开头的路径、相对路径或模块路径(例如 github.com/nging-plugins/collector@/template/)
+	Plugins              map[string][]string // key: `*` 或 VendorMiscDirs 中的 key; value: 插件模块路径(例如 github.com/nging-plugins/collector)。用于生成 plugins_gen*.go 并打包插件的资源目录，配置后不再自动发现其它插件
+	PluginModulePrefixes []string            // 从 vendor/modules.txt 自动发现插件资源目录的模块路径前缀，为空时不自动发现
 	AutoDiscoveryMiscDir bool
+	AssetDirs            []string // 资源目录约定(相对于项目目录或插件目录)，默认: public/assets、template、config/i18n
 	BuildTags            []string
 	CopyFiles            []string
 	MakeDirs             []string
-	Compiler             string
+	Compiler             string                  // go / xgo / auto
+	CompilerChains       map[string][]string     // key: 目标匹配模式(例如 `*`、`linux/*`); value: 按优先级排列的编译器
+	CompilerRules        map[string]CompilerRule // key: 编译器名称
 	CgoEnabled           bool
-	Targets              map[string]string
+	Targets              map[string]string   // key: 别名; value: ${GOOS}/${GOARCH}
+	DefaultTargets       []string            // 未指定目标时默认构建的目标，为空时采用内置列表
+	TargetGroups         map[string][]string // key: 组名; value: 目标表达式(例如 server: ["linux/amd64,linux/arm64"])
 	BindataIgnore        []string
+	AllowAssetCollisions []string            // 允许多个源文件对应相同资源路径的路径(支持通配符，例如 template/common/*)
+	AssetGenerator       string              // 资源生成方式: bindata(默认) 或 embed
+	Bindata              BindataConfig       // 资源生成工具(go-bindata)配置
+	I18n                 I18nConfig          // 语言文件校验配置
+	TemplateCheck        TemplateCheckConfig // 模板语法校验配置
 	CompressLevel        int
+	LockTimeout          int           // 写入打包目录或构建同一目标时等待其它构建进程释放锁的时间(秒)，默认: 120；为 0 时立即失败，小于 0 时一直等待
+	Timeouts             StageTimeouts // 各构建阶段的超时时间
+	CompressThreads      int           // 打包时并行压缩的线程数，默认: CPU 核数；为 1 时不并行压缩
+	ChecksumAlgorithms   []string      // 除 sha256 外额外生成的校验和算法: sha512、sha1、md5
 	BindataLevel         int
+	CacheDir             string            // 缓存目录，默认: 用户缓存目录下的 nging-builder
+	RemoteCache          RemoteCacheConfig // 远程构建缓存配置
+	Hooks                Hooks             // 全局钩子
+	TargetHooks          map[string]Hooks  // 目标钩子。key: 目标匹配模式(例如 `linux/*`、`windows_amd64`)
 }
 
 func (a Config) Clone() Config {
@@ -893,28 +1158,71 @@ func (a Config) Clone() Config {
 		StartupPackage:       a.StartupPackage,
 		Project:              a.Project,
 		VendorMiscDirs:       map[string][]string{}, // key: GOOS
+		Plugins:              map[string][]string{},
+		PluginModulePrefixes: make([]string, len(a.PluginModulePrefixes)),
 		AutoDiscoveryMiscDir: a.AutoDiscoveryMiscDir,
+		AssetDirs:            make([]string, len(a.AssetDirs)),
 		BuildTags:            make([]string, len(a.BuildTags)),
 		CopyFiles:            make([]string, len(a.CopyFiles)),
 		MakeDirs:             make([]string, len(a.MakeDirs)),
 		Compiler:             a.Compiler,
+		CompilerChains:       map[string][]string{},
+		CompilerRules:        map[string]CompilerRule{},
 		CgoEnabled:           a.CgoEnabled,
 		Targets:              map[string]string{},
+		DefaultTargets:       make([]string, len(a.DefaultTargets)),
+		TargetGroups:         map[string][]string{},
 		BindataIgnore:        make([]string, len(a.BindataIgnore)),
+		AllowAssetCollisions: make([]string, len(a.AllowAssetCollisions)),
+		AssetGenerator:       a.AssetGenerator,
+		Bindata:              a.Bindata.Clone(),
+		I18n:                 a.I18n,
+		TemplateCheck:        a.TemplateCheck.Clone(),
 		CompressLevel:        a.CompressLevel,
+		LockTimeout:          a.LockTimeout,
+		Timeouts:             a.Timeouts,
+		CompressThreads:      a.CompressThreads,
+		ChecksumAlgorithms:   make([]string, len(a.ChecksumAlgorithms)),
 		BindataLevel:         a.BindataLevel,
+		CacheDir:             a.CacheDir,
+		RemoteCache:          a.RemoteCache.Clone(),
+		Hooks:                a.Hooks.Clone(),
+		TargetHooks:          map[string]Hooks{},
 	}
 	copy(c.BuildTags, a.BuildTags)
 	copy(c.CopyFiles, a.CopyFiles)
 	copy(c.MakeDirs, a.MakeDirs)
 	copy(c.BindataIgnore, a.BindataIgnore)
+	copy(c.AllowAssetCollisions, a.AllowAssetCollisions)
+	copy(c.DefaultTargets, a.DefaultTargets)
+	copy(c.PluginModulePrefixes, a.PluginModulePrefixes)
+	copy(c.AssetDirs, a.AssetDirs)
+	copy(c.ChecksumAlgorithms, a.ChecksumAlgorithms)
 	for k, v := range a.VendorMiscDirs {
 		c.VendorMiscDirs[k] = make([]string, len(v))
 		copy(c.VendorMiscDirs[k], v)
 	}
+	for k, v := range a.Plugins {
+		c.Plugins[k] = make([]string, len(v))
+		copy(c.Plugins[k], v)
+	}
 	for k, v := range a.Targets {
 		c.Targets[k] = v
 	}
+	for k, v := range a.TargetGroups {
+		c.TargetGroups[k] = make([]string, len(v))
+		copy(c.TargetGroups[k], v)
+	}
+	for k, v := range a.CompilerChains {
+		c.CompilerChains[k] = make([]string, len(v))
+		copy(c.CompilerChains[k], v)
+	}
+	for k, v := range a.CompilerRules {
+		c.CompilerRules[k] = v.Clone()
+	}
+	for k, v := range a.TargetHooks {
+		c.TargetHooks[k] = v.Clone()
+	}
 	return c
 }
 
@@ -940,23 +1248,52 @@ func (a Config) apply() {
 		p.VendorMiscDirs = a.VendorMiscDirs
 	}
 	p.AutoDiscoveryMiscDir = a.AutoDiscoveryMiscDir
+	p.AssetDirs = a.AssetDirs
+	p.Plugins = a.Plugins
+	p.PluginModulePrefixes = a.PluginModulePrefixes
 	if len(a.Targets) > 0 {
 		for k, v := range a.Targets {
 			targetNames[k] = v
 		}
 	}
+	p.Targets = a.Targets
+	p.DefaultTargets = a.DefaultTargets
+	p.TargetGroups = a.TargetGroups
 	if len(a.BindataIgnore) > 0 {
 		p.BindataIgnore = a.BindataIgnore
 	}
+	p.AllowAssetCollisions = a.AllowAssetCollisions
 	p.GoImage = a.GoImage
 	p.BuildTags = a.BuildTags
 	p.CopyFiles = a.CopyFiles
 	p.MakeDirs = a.MakeDirs
 	p.Compiler = a.Compiler
+	if len(a.CompilerChains) > 0 {
+		p.CompilerChains = a.CompilerChains
+	} else {
+		p.CompilerChains = defaultCompilerChains
+	}
+	if len(a.CompilerRules) > 0 {
+		p.CompilerRules = a.CompilerRules
+	} else {
+		p.CompilerRules = defaultCompilerRules
+	}
 	p.CgoEnabled = a.CgoEnabled
 	p.GoProxy = a.GoProxy
 	p.CompressLevel = a.CompressLevel
+	p.LockTimeout = a.LockTimeout
+	p.Timeouts = a.Timeouts
+	p.CompressThreads = a.CompressThreads
+	p.ChecksumAlgorithms = a.ChecksumAlgorithms
 	p.BindataLevel = a.BindataLevel
+	p.AssetGenerator = a.AssetGenerator
+	p.Bindata = a.Bindata
+	p.I18n = a.I18n
+	p.TemplateCheck = a.TemplateCheck
+	p.CacheDir = a.CacheDir
+	p.RemoteCache = a.RemoteCache
+	p.Hooks = a.Hooks
+	p.TargetHooks = a.TargetHooks
 }
 
 func sha256file(file string) (string, error) {
@@ -977,45 +1314,4 @@ func sha256file(file string) (string, error) {
 	return sha256Result, nil
 }
 
-func makeChecksum(file string) error {
-	sha256Result, err := sha256file(file)
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(file+`.sha256`, []byte(sha256Result+` `+filepath.Base(file)), 0666)
-}
-
 var spaceRegexp = regexp.MustCompile(`\s+`)
-
-func makeChecksums(files []string, saveDir string) error {
-	saveFile := `checksums.txt`
-	if len(saveDir) > 0 {
-		os.MkdirAll(saveDir, os.ModePerm)
-		saveFile = filepath.Join(saveDir, saveFile)
-	}
-	checksums := map[string]string{}
-	b, err := os.ReadFile(saveFile)
-	if err == nil {
-		lines := strings.Split(string(b), "\n")
-		for _, line := range lines {
-			parts := spaceRegexp.Split(line, 2)
-			if len(parts) == 2 {
-				checksums[parts[1]] = parts[0]
-			}
-		}
-	}
-	lines := make([]string, len(files))
-	for index, file := range files {
-		sha256Result, err := sha256file(file)
-		if err != nil {
-			return err
-		}
-		fileName := filepath.Base(file)
-		lines[index] = sha256Result + ` ` + fileName
-		delete(checksums, fileName)
-	}
-	for fileName, sha256Result := range checksums {
-		lines = append(lines, sha256Result+` `+fileName)
-	}
-	return os.WriteFile(saveFile, []byte(strings.Join(lines, "\n")), 0666)
-}
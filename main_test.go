@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,7 +15,7 @@ import (
 func Test1(t *testing.T) {
 	miscDirs := []string{`../../../github.com/admpub/nging/template/...`}
 	var prefixes []string
-	prefixes, miscDirs = buildGoGenerateCommandData(miscDirs)
+	prefixes, miscDirs = buildGoGenerateCommandData(context.Background(), miscDirs)
 	b, _ := json.MarshalIndent(miscDirs, ``, `  `)
 	fmt.Println(string(b))
 	b, _ = json.MarshalIndent(prefixes, ``, `  `)
@@ -46,3 +49,58 @@ func Test3(t *testing.T) {
 		assert.True(t, templateAndPublicMisc.MatchString(dir))
 	}
 }
+
+func TestModuleMiscDir(t *testing.T) {
+	assert.Equal(t, `-mod=readonly`, withReadonlyModFlag(``))
+	assert.Equal(t, `-trimpath -mod=vendor -mod=readonly`, withReadonlyModFlag(`-trimpath -mod=vendor`))
+
+	original := resolveModuleDir
+	defer func() {
+		resolveModuleDir = original
+	}()
+	resolveModuleDir = func(_ context.Context, projectPath string, modulePath string, version string) (string, error) {
+		return filepath.Join(filepath.Dir(projectPath), `pkg`, `mod`, modulePath+`@v1.2.3`), nil
+	}
+	p.ProjectPath = filepath.Join(`/go`, `src`)
+	defer func() {
+		p.ProjectPath = ``
+	}()
+	prefixes, miscDirs := buildGoGenerateCommandData(context.Background(), []string{
+		`github.com/nging-plugins/collector@/template/`,
+		`github.com/nging-plugins/collector@/public/assets/`,
+		`vendor/github.com/nging-plugins/dbmanager/template/`,
+	})
+	assert.Equal(t, []string{
+		`../pkg/mod/github.com/nging-plugins/collector@v1.2.3/template/...`,
+		`../pkg/mod/github.com/nging-plugins/collector@v1.2.3/public/assets/...`,
+		`vendor/github.com/nging-plugins/dbmanager/template/...`,
+	}, miscDirs)
+	assert.Equal(t, []string{
+		`../pkg/mod/github.com/nging-plugins/collector@v1.2.3/`,
+		`vendor/github.com/nging-plugins/dbmanager/`,
+	}, prefixes)
+}
+
+func TestGenCommentBindataConfig(t *testing.T) {
+	defer func() {
+		p.Bindata = BindataConfig{}
+	}()
+	comment := genComment(context.Background(), nil)
+	assert.True(t, strings.HasPrefix(comment, "//go:generate go install github.com/admpub/bindata/v3/go-bindata@v3.1.5\n//go:generate go-bindata -fs -o bindata_assetfs.go "))
+	assert.Contains(t, comment, ` -ignore "\\.(git|svn|DS_Store|less|scss|gitkeep|go)$" -minify "\\.(js|css)$" -tags bindata -prefix`)
+
+	p.Bindata = BindataConfig{
+		Module:     `github.com/admpub/bindata/v3/go-bindata`,
+		Version:    `v3.2.0`,
+		Output:     `assets_gen.go`,
+		Minify:     `-`,
+		Tags:       `bindata && !dev`,
+		ExtraFlags: []string{`-nometadata`},
+	}
+	comment = genComment(context.Background(), nil)
+	assert.True(t, strings.HasPrefix(comment, "//go:generate go install github.com/admpub/bindata/v3/go-bindata@v3.2.0\n//go:generate go-bindata -fs -o assets_gen.go "))
+	assert.NotContains(t, comment, `-minify`)
+	assert.Contains(t, comment, ` -tags "bindata && !dev" -nometadata -prefix`)
+	assert.Equal(t, `go-bindata`, BindataConfig{Module: `github.com/admpub/bindata/v3/go-bindata`}.Command())
+	assert.Equal(t, `tool`, BindataConfig{Module: `example.com/tool/v2`}.Command())
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/webx-top/com"
+)
+
+// 默认的资源目录约定(相对于项目目录或插件目录)
+var defaultAssetDirs = []string{`public/assets`, `template`, `config/i18n`}
+
+// assetDirs 返回资源目录约定，未配置 AssetDirs 时采用默认值
+func assetDirs() []string {
+	dirs := p.AssetDirs
+	if len(dirs) == 0 {
+		dirs = defaultAssetDirs
+	}
+	result := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		dir = strings.Trim(path.Clean(strings.TrimSpace(dir)), `/`)
+		if len(dir) == 0 || dir == `.` || slices.Contains(result, dir) {
+			continue
+		}
+		result = append(result, dir)
+	}
+	return result
+}
+
+// assetDirRegexp 返回匹配以资源目录结尾的路径的正则表达式
+func assetDirRegexp(dirs []string) *regexp.Regexp {
+	quoted := make([]string, len(dirs))
+	for index, dir := range dirs {
+		quoted[index] = regexp.QuoteMeta(dir)
+	}
+	return regexp.MustCompile(`(/|^)(` + strings.Join(quoted, `|`) + `)(/|/[.]{3})?$`)
+}
+
+// projectMiscDirs 返回项目自身存在的资源目录
+func projectMiscDirs() []string {
+	dirs := assetDirs()
+	miscDirs := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if !com.IsDir(filepath.Join(p.ProjectPath, filepath.FromSlash(dir))) {
+			continue
+		}
+		miscDirs = append(miscDirs, dir+`/`)
+	}
+	return miscDirs
+}
+
+// miscDirKeys 返回排序后的 VendorMiscDirs key(不包括 `*`)
+func miscDirKeys(vendorMiscDirs map[string][]string) []string {
+	keys := make([]string, 0, len(vendorMiscDirs))
+	for key := range vendorMiscDirs {
+		if key != `*` {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// miscDirsOfKey 返回 VendorMiscDirs 中指定 key 的资源目录(包括 `*` 中的公共资源目录)
+func miscDirsOfKey(vendorMiscDirs map[string][]string, key string) []string {
+	dirs := make([]string, 0, len(vendorMiscDirs[`*`])+len(vendorMiscDirs[key]))
+	dirs = append(dirs, vendorMiscDirs[`*`]...)
+	dirs = append(dirs, vendorMiscDirs[key]...)
+	return dirs
+}
+
+// miscDirsNamed 返回所有 VendorMiscDirs key 的资源目录中以 name 结尾的目录(例如 config/i18n)
+func miscDirsNamed(ctx context.Context, vendorMiscDirs map[string][]string, name string) []string {
+	var dirs []string
+	for _, key := range miscDirKeys(vendorMiscDirs) {
+		_, miscDirs := genMiscDirs(ctx, miscDirsOfKey(vendorMiscDirs, key)...)
+		for _, dir := range miscDirs {
+			dir = strings.TrimSuffix(strings.TrimSuffix(filepath.ToSlash(dir), `...`), `/`)
+			if dir != name && !strings.HasSuffix(dir, `/`+name) {
+				continue
+			}
+			if !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// listMiscDirs 输出每个 VendorMiscDirs key 最终需要打包的资源目录及需要去除的前缀
+func listMiscDirs(ctx context.Context, w io.Writer) {
+	vendorMiscDirs := vendorMiscDirsWithPlugins(ctx)
+	fmt.Fprintln(w, `AssetDirs	:	`, strings.Join(assetDirs(), `, `))
+	for _, key := range miscDirKeys(vendorMiscDirs) {
+		prefixes, miscDirs := genMiscDirs(ctx, miscDirsOfKey(vendorMiscDirs, key)...)
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, `[`+normalizeMiscDirKey(key)+`]`)
+		for _, prefix := range prefixes {
+			fmt.Fprintln(w, `  prefix	:	`, prefix)
+		}
+		for _, dir := range miscDirs {
+			fmt.Fprintln(w, `  dir		:	`, dir)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetDirs(t *testing.T) {
+	original, projectPath := p.AssetDirs, p.ProjectPath
+	defer func() {
+		p.AssetDirs, p.ProjectPath = original, projectPath
+	}()
+	p.AssetDirs = nil
+	assert.Equal(t, defaultAssetDirs, assetDirs())
+	p.ProjectPath = t.TempDir()
+	writeTestFiles(t, p.ProjectPath, map[string]string{
+		`public/assets/style.css`:   `style`,
+		`config/i18n/en/index.yaml`: `en`,
+	})
+	// 不存在的目录(template)不参与打包
+	assert.Equal(t, []string{`public/assets/`, `config/i18n/`}, projectMiscDirs())
+
+	p.AssetDirs = []string{`template/`, ` public/static `, `/config/rules/`, `template`, ``, `data/schema`}
+	assert.Equal(t, []string{`template`, `public/static`, `config/rules`, `data/schema`}, assetDirs())
+
+	re := assetDirRegexp(assetDirs())
+	assert.True(t, re.MatchString(`public/static/`))
+	assert.True(t, re.MatchString(`vendor/github.com/nging-plugins/collector/data/schema/...`))
+	assert.False(t, re.MatchString(`public/assets/`))
+	assert.False(t, re.MatchString(`config/rules_old/`))
+}
+
+func TestAutoDiscoveryAssetDirs(t *testing.T) {
+	originalDirs, originalAuto := p.AssetDirs, p.AutoDiscoveryMiscDir
+	defer func() {
+		p.AssetDirs, p.AutoDiscoveryMiscDir = originalDirs, originalAuto
+	}()
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`plugin/template/index.html`:   `index`,
+		`plugin/public/static/app.js`:  `js`,
+		`plugin/config/rules/a.yaml`:   `a`,
+		`plugin/public/assets/app.css`: `css`,
+	})
+	pluginDir := filepath.ToSlash(filepath.Join(root, `plugin`)) + `/`
+	p.AutoDiscoveryMiscDir = true
+	p.AssetDirs = []string{`template`, `public/static`, `config/rules`, `data/schema`}
+	_, miscDirs := buildGoGenerateCommandData(context.Background(), []string{pluginDir, pluginDir + `public/static/`})
+	assert.Equal(t, []string{
+		filepath.Join(root, `plugin`, `template`) + `/...`,
+		filepath.Join(root, `plugin`, `public/static`) + `/...`,
+		filepath.Join(root, `plugin`, `config/rules`) + `/...`,
+		pluginDir + `public/static/...`,
+	}, miscDirs)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// isModuleMiscDir 是否为模块路径格式的资源目录，例如: github.com/nging-plugins/collector@/template/ 或 github.com/nging-plugins/collector@v1.2.3/template/
+func isModuleMiscDir(dir string) bool {
+	if strings.HasPrefix(dir, `vendor/`) || strings.HasPrefix(dir, `.`) || filepath.IsAbs(dir) {
+		return false
+	}
+	return strings.Contains(dir, `@`)
+}
+
+// parseModuleMiscDir 解析模块路径格式的资源目录，返回模块路径、版本号和模块内的子目录
+func parseModuleMiscDir(dir string) (modulePath string, version string, subDir string) {
+	modulePath, rest, _ := strings.Cut(dir, `@`)
+	version, subDir, _ = strings.Cut(rest, `/`)
+	return
+}
+
+type moduleDirKey struct {
+	projectPath string
+	modulePath  string
+	version     string
+}
+
+var (
+	moduleDirs   = map[moduleDirKey]string{}
+	moduleDirsMu sync.Mutex
+)
+
+// resolveModuleDir 通过 `go list -m -json` 获取模块所在目录(GOMODCACHE 中的目录或 replace 指定的目录)
+var resolveModuleDir = func(ctx context.Context, projectPath string, modulePath string, version string) (string, error) {
+	key := moduleDirKey{projectPath: projectPath, modulePath: modulePath, version: version}
+	moduleDirsMu.Lock()
+	defer moduleDirsMu.Unlock()
+	if dir, ok := moduleDirs[key]; ok {
+		return dir, nil
+	}
+	query := modulePath
+	if len(version) > 0 {
+		query += `@` + version
+	}
+	cmd := exec.CommandContext(ctx, `go`, `list`, `-m`, `-json`, query)
+	cmd.Dir = projectPath
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), `GOFLAGS=`+withReadonlyModFlag(goFlags(ctx, projectPath)))
+	out, err := cmd.Output()
+	if err != nil {
+		return ``, fmt.Errorf(`failed to resolve module %q: %w`, query, err)
+	}
+	var m struct {
+		Dir     string
+		Replace *struct {
+			Dir string
+		}
+	}
+	err = json.Unmarshal(out, &m)
+	if err != nil {
+		return ``, err
+	}
+	dir := m.Dir
+	if m.Replace != nil && len(m.Replace.Dir) > 0 {
+		dir = m.Replace.Dir
+	}
+	if len(dir) == 0 {
+		return ``, fmt.Errorf(`module %q is not downloaded`, query)
+	}
+	moduleDirs[key] = dir
+	return dir, nil
+}
+
+// goFlags 返回当前生效的 GOFLAGS(包括通过 go env -w 设置的值)
+func goFlags(ctx context.Context, projectPath string) string {
+	cmd := exec.CommandContext(ctx, `go`, `env`, `GOFLAGS`)
+	cmd.Dir = projectPath
+	out, err := cmd.Output()
+	if err != nil {
+		return os.Getenv(`GOFLAGS`)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// withReadonlyModFlag 在 GOFLAGS 后追加 -mod=readonly(覆盖其中的 -mod 参数)，保留用户设置的其它参数。
+// 存在 vendor 目录时也能查询模块，且不会修改 go.mod/go.sum
+func withReadonlyModFlag(flags string) string {
+	return strings.TrimSpace(flags + ` -mod=readonly`)
+}
+
+// resolveModuleMiscDir 将模块路径格式的资源目录转换为相对于项目目录的实际路径，
+// 并返回需要去除的前缀(模块目录)，使资源路径与 vendor 模式下保持一致
+func resolveModuleMiscDir(ctx context.Context, projectPath string, miscDir string) (dir string, prefix string, err error) {
+	modulePath, version, subDir := parseModuleMiscDir(miscDir)
+	moduleDir, err := resolveModuleDir(ctx, projectPath, modulePath, version)
+	if err != nil {
+		return
+	}
+	if rel, relErr := filepath.Rel(projectPath, moduleDir); relErr == nil {
+		moduleDir = rel
+	}
+	prefix = filepath.ToSlash(moduleDir) + `/`
+	dir = prefix + subDir
+	return
+}
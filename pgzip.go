@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"runtime"
+)
+
+const (
+	gzipBlockSize  = 1 << 20 // 每个并行压缩块的大小
+	gzipWindowSize = 32 << 10
+)
+
+// newGzipWriter 创建 gzip 压缩器。threads 为 1 时使用标准库的单线程压缩，小于 1 时使用 CPU 核数
+func newGzipWriter(w io.Writer, level int, threads int) (io.WriteCloser, error) {
+	if threads < 1 {
+		threads = runtime.NumCPU()
+	}
+	if threads == 1 {
+		return gzip.NewWriterLevel(w, level)
+	}
+	return newParallelGzipWriter(w, level, threads, gzipBlockSize)
+}
+
+// gzipBlock 独立压缩的数据块。以上一个块的最后 32KB 作为字典，压缩率接近单线程压缩
+type gzipBlock struct {
+	data []byte
+	dict []byte
+	last bool
+	out  bytes.Buffer
+	err  error
+	done chan struct{}
+}
+
+func (b *gzipBlock) compress(level int) {
+	defer close(b.done)
+	fw, err := flate.NewWriterDict(&b.out, level, b.dict)
+	if err != nil {
+		b.err = err
+		return
+	}
+	if _, err = fw.Write(b.data); err != nil {
+		b.err = err
+		return
+	}
+	// 非最后一个块以 sync flush 结束(字节对齐且不是最终块)，因此各块输出可以直接拼接为一个 deflate 流
+	if b.last {
+		b.err = fw.Close()
+	} else {
+		b.err = fw.Flush()
+	}
+}
+
+// parallelGzipWriter 多线程 gzip 压缩器(与 pgzip 相同的原理)，输出标准的单成员 gzip 流
+type parallelGzipWriter struct {
+	w         io.Writer
+	level     int
+	blockSize int
+	sem       chan struct{}
+	queue     []*gzipBlock
+	buf       []byte
+	dict      []byte
+	crc       uint32
+	size      uint32
+	err       error
+	closed    bool
+}
+
+func newParallelGzipWriter(w io.Writer, level int, threads int, blockSize int) (*parallelGzipWriter, error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return nil, fmt.Errorf(`gzip: invalid compression level: %d`, level)
+	}
+	z := &parallelGzipWriter{
+		w:         w,
+		level:     level,
+		blockSize: blockSize,
+		sem:       make(chan struct{}, threads),
+	}
+	// 与 compress/gzip 相同的文件头: 无文件名、修改时间为 0
+	header := [10]byte{0x1f, 0x8b, 8, 0, 0, 0, 0, 0, 0, 255}
+	if level == gzip.BestCompression {
+		header[8] = 2
+	} else if level == gzip.BestSpeed {
+		header[8] = 4
+	}
+	if _, err := w.Write(header[:]); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+func (z *parallelGzipWriter) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	n := len(p)
+	z.crc = crc32.Update(z.crc, crc32.IEEETable, p)
+	z.size += uint32(n)
+	for len(p) > 0 {
+		if z.buf == nil {
+			z.buf = make([]byte, 0, z.blockSize)
+		}
+		m := min(z.blockSize-len(z.buf), len(p))
+		z.buf = append(z.buf, p[:m]...)
+		p = p[m:]
+		if len(z.buf) == z.blockSize {
+			if err := z.dispatch(false); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// dispatch 将当前缓冲区作为一个块交给后台压缩，排队的块过多时先按顺序写出已完成的块
+func (z *parallelGzipWriter) dispatch(last bool) error {
+	b := &gzipBlock{data: z.buf, dict: z.dict, last: last, done: make(chan struct{})}
+	if len(b.data) >= gzipWindowSize {
+		z.dict = b.data[len(b.data)-gzipWindowSize:]
+	} else {
+		dict := make([]byte, 0, gzipWindowSize)
+		dict = append(dict, z.dict[max(0, len(z.dict)+len(b.data)-gzipWindowSize):]...)
+		z.dict = append(dict, b.data...)
+	}
+	z.buf = nil
+	z.queue = append(z.queue, b)
+	z.sem <- struct{}{}
+	go func() {
+		defer func() { <-z.sem }()
+		b.compress(z.level)
+	}()
+	for len(z.queue) > cap(z.sem) {
+		if err := z.writeOldest(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (z *parallelGzipWriter) writeOldest() error {
+	b := z.queue[0]
+	z.queue = z.queue[1:]
+	<-b.done
+	if b.err == nil {
+		_, b.err = z.w.Write(b.out.Bytes())
+	}
+	if b.err != nil && z.err == nil {
+		z.err = b.err
+	}
+	return z.err
+}
+
+// Close 写出剩余的块及 gzip 文件尾。不关闭底层的 io.Writer
+func (z *parallelGzipWriter) Close() error {
+	if z.closed {
+		return z.err
+	}
+	z.closed = true
+	if z.err != nil {
+		return z.err
+	}
+	if err := z.dispatch(true); err != nil {
+		return err
+	}
+	for len(z.queue) > 0 {
+		if err := z.writeOldest(); err != nil {
+			return err
+		}
+	}
+	var trailer [8]byte
+	binary.LittleEndian.PutUint32(trailer[:4], z.crc)
+	binary.LittleEndian.PutUint32(trailer[4:], z.size)
+	_, z.err = z.w.Write(trailer[:])
+	return z.err
+}
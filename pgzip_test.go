@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testGzipData 生成可压缩的测试数据
+func testGzipData(size int) []byte {
+	words := strings.Fields(`nging builder release target linux darwin windows amd64 arm64 template assets bindata vendor plugin`)
+	r := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for buf.Len() < size {
+		buf.WriteString(words[r.Intn(len(words))])
+		buf.WriteByte(byte(' ' + r.Intn(3)))
+		if r.Intn(20) == 0 {
+			buf.WriteByte(byte(r.Intn(256)))
+		}
+	}
+	return buf.Bytes()[:size]
+}
+
+func gunzip(t *testing.T, b []byte) []byte {
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	assert.NoError(t, err)
+	gr.Multistream(false)
+	out, err := io.ReadAll(gr)
+	assert.NoError(t, err)
+	// 单成员 gzip 流
+	rest, _ := io.ReadAll(gr)
+	assert.Empty(t, rest)
+	return out
+}
+
+func TestParallelGzipWriter(t *testing.T) {
+	data := testGzipData(300 << 10)
+	for _, level := range []int{gzip.NoCompression, gzip.BestSpeed, gzip.DefaultCompression, gzip.BestCompression, gzip.HuffmanOnly} {
+		var buf bytes.Buffer
+		z, err := newParallelGzipWriter(&buf, level, 4, 20<<10)
+		assert.NoError(t, err)
+		// 写入大小与块大小不对齐
+		for chunk := data; len(chunk) > 0; {
+			n := min(7777, len(chunk))
+			_, err = z.Write(chunk[:n])
+			assert.NoError(t, err)
+			chunk = chunk[n:]
+		}
+		assert.NoError(t, z.Close())
+		assert.Equal(t, data, gunzip(t, buf.Bytes()), `level %d`, level)
+
+		// 压缩率接近单线程压缩
+		var std bytes.Buffer
+		gw, err := gzip.NewWriterLevel(&std, level)
+		assert.NoError(t, err)
+		gw.Write(data)
+		assert.NoError(t, gw.Close())
+		assert.Less(t, float64(buf.Len()), float64(std.Len())*1.02, `level %d`, level)
+	}
+}
+
+func TestParallelGzipWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	z, err := newParallelGzipWriter(&buf, gzip.BestCompression, 2, gzipBlockSize)
+	assert.NoError(t, err)
+	assert.NoError(t, z.Close())
+	assert.Empty(t, gunzip(t, buf.Bytes()))
+
+	_, err = newParallelGzipWriter(&buf, 10, 2, gzipBlockSize)
+	assert.EqualError(t, err, `gzip: invalid compression level: 10`)
+
+	w, err := newGzipWriter(&buf, gzip.BestCompression, 1)
+	assert.NoError(t, err)
+	assert.IsType(t, &gzip.Writer{}, w)
+}
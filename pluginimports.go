@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/webx-top/com"
+)
+
+// 插件导入文件的文件名前缀。Plugins 中 `*` 对应 plugins_gen.go，其它 key 对应 plugins_gen_<suffix>.go
+const pluginImportFilePrefix = `plugins_gen`
+
+// configuredPluginPaths 返回 Plugins 中配置的所有插件模块路径
+func configuredPluginPaths() []string {
+	var paths []string
+	for _, modules := range p.Plugins {
+		for _, modulePath := range modules {
+			if !slices.Contains(paths, modulePath) {
+				paths = append(paths, modulePath)
+			}
+		}
+	}
+	return paths
+}
+
+// configuredPluginModule 查找插件模块目录，优先使用 vendor 目录
+func configuredPluginModule(ctx context.Context, modulePath string) (pluginModule, error) {
+	m := pluginModule{Path: modulePath}
+	vendorDir := filepath.Join(p.ProjectPath, `vendor`, filepath.FromSlash(modulePath))
+	if com.IsDir(vendorDir) {
+		m.Dir = vendorDir
+		m.Vendor = true
+		return m, nil
+	}
+	dir, err := resolveModuleDir(ctx, p.ProjectPath, modulePath, ``)
+	if err != nil {
+		return m, err
+	}
+	m.Dir = dir
+	return m, nil
+}
+
+// configuredPluginMiscDirs 返回 Plugins 中各个插件的资源目录，按 Plugins 的 key 归类
+func configuredPluginMiscDirs(ctx context.Context) (map[string][]string, error) {
+	result := map[string][]string{}
+	for key, modules := range p.Plugins {
+		for _, modulePath := range modules {
+			m, err := configuredPluginModule(ctx, modulePath)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = append(result[key], pluginAssetMiscDirs(m)...)
+		}
+	}
+	return result, nil
+}
+
+// pluginImportFileName 返回 Plugins 中的 key 对应的插件导入文件名
+func pluginImportFileName(key string) string {
+	if key == `*` {
+		return pluginImportFilePrefix + `.go`
+	}
+	return pluginImportFilePrefix + `_` + miscDirKeyFileSuffix(key) + `.go`
+}
+
+// pluginImportSource 生成以空白导入方式引入插件的 Go 文件
+func pluginImportSource(key string, modules []string) string {
+	var src string
+	if key != `*` {
+		src += "//go:build " + normalizeMiscDirKey(key) + "\n\n"
+	}
+	src += generatedCodeComment + "\n\n"
+	src += "package main\n"
+	modules = slices.Clone(modules)
+	slices.Sort(modules)
+	modules = slices.Compact(modules)
+	if len(modules) == 0 {
+		return src
+	}
+	src += "\nimport (\n"
+	for _, modulePath := range modules {
+		src += "\t_ " + fmt.Sprintf(`%q`, modulePath) + "\n"
+	}
+	src += ")\n"
+	return src
+}
+
+// buildPluginImportFiles 生成 Plugins 中每个 key 对应的插件导入文件
+func buildPluginImportFiles(plugins map[string][]string) ([]generatedFile, error) {
+	keys := make([]string, 0, len(plugins))
+	for key := range plugins {
+		if key != `*` {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	if err := checkMiscDirKeys(keys, p.buildTags()); err != nil {
+		return nil, fmt.Errorf(`Plugins: %w`, err)
+	}
+	if _, ok := plugins[`*`]; ok {
+		keys = append([]string{`*`}, keys...)
+	}
+	files := make([]generatedFile, 0, len(keys))
+	for _, key := range keys {
+		files = append(files, generatedFile{
+			Path:    filepath.Join(p.ProjectPath, pluginImportFileName(key)),
+			Content: pluginImportSource(key, plugins[key]),
+			OSName:  key,
+		})
+	}
+	return files, nil
+}
+
+// isPluginImportFile 是否为插件导入文件
+func isPluginImportFile(file string) bool {
+	return strings.HasPrefix(filepath.Base(file), pluginImportFilePrefix)
+}
+
+// removeStalePluginImportFiles 删除不再对应 Plugins 中任何 key 的插件导入文件
+func removeStalePluginImportFiles(files []generatedFile) {
+	existing, _ := filepath.Glob(filepath.Join(p.ProjectPath, pluginImportFilePrefix+`*.go`))
+	for _, file := range existing {
+		if slices.ContainsFunc(files, func(f generatedFile) bool {
+			return f.Path == file
+		}) {
+			continue
+		}
+		b, err := os.ReadFile(file)
+		if err != nil || !strings.Contains(string(b), generatedCodeComment) {
+			continue
+		}
+		fmt.Println(`[plugins]	:	 remove`, file)
+		if err := os.Remove(file); err != nil {
+			fmt.Println(err.Error())
+		}
+	}
+}
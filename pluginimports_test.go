@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPluginImportSource(t *testing.T) {
+	assert.Equal(t, `plugins_gen.go`, pluginImportFileName(`*`))
+	assert.Equal(t, `plugins_gen_linux.go`, pluginImportFileName(`linux`))
+	assert.Equal(t, `plugins_gen_nonlinux.go`, pluginImportFileName(`!linux`))
+
+	assert.Equal(t, `// Code generated by nging-builder. DO NOT EDIT.
+
+package main
+
+import (
+	_ "github.com/nging-plugins/collector"
+	_ "github.com/nging-plugins/dbmanager"
+)
+`, pluginImportSource(`*`, []string{`github.com/nging-plugins/dbmanager`, `github.com/nging-plugins/collector`, `github.com/nging-plugins/dbmanager`}))
+
+	assert.Equal(t, `//go:build linux && (amd64 || arm64)
+
+// Code generated by nging-builder. DO NOT EDIT.
+
+package main
+`, pluginImportSource(`linux && (amd64||arm64)`, nil))
+}
+
+func TestConfiguredPlugins(t *testing.T) {
+	original := p.Clone()
+	originalResolve := resolveModuleDir
+	defer func() {
+		p = original
+		resolveModuleDir = originalResolve
+	}()
+	root := t.TempDir()
+	modCache := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`vendor/github.com/nging-plugins/collector/template/index.html`: `collector`,
+		`vendor/github.com/nging-plugins/collector/config/i18n/en.yaml`: `a: b`,
+		`plugins_gen_windows.go`: "// Code generated by nging-builder. DO NOT EDIT.\n\npackage main\n",
+		`plugins_gen_custom.go`:  "package main\n",
+	})
+	writeTestFiles(t, modCache, map[string]string{
+		`firewallmanager/public/assets/app.js`: `js`,
+	})
+	resolveModuleDir = func(_ context.Context, projectPath string, modulePath string, version string) (string, error) {
+		return filepath.Join(modCache, filepath.Base(modulePath)), nil
+	}
+	p.ProjectPath = root
+	p.AssetDirs = nil
+	p.Plugins = map[string][]string{
+		`*`:     {`github.com/nging-plugins/collector`},
+		`linux`: {`github.com/nging-plugins/firewallmanager`},
+	}
+	assert.ElementsMatch(t, []string{`github.com/nging-plugins/collector`, `github.com/nging-plugins/firewallmanager`}, configuredPluginPaths())
+
+	miscDirs, err := configuredPluginMiscDirs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		`*`:     {`vendor/github.com/nging-plugins/collector/template/`, `vendor/github.com/nging-plugins/collector/config/i18n/`},
+		`linux`: {`github.com/nging-plugins/firewallmanager@/public/assets/`},
+	}, miscDirs)
+
+	// 配置了 Plugins 时不再自动发现其它插件
+	p.PluginModulePrefixes = defaultPluginModulePrefixes
+	discovered, err := discoverPluginMiscDirs(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, discovered)
+
+	files, err := buildPluginImportFiles(p.Plugins)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, filepath.Join(root, `plugins_gen.go`), files[0].Path)
+	assert.Equal(t, filepath.Join(root, `plugins_gen_linux.go`), files[1].Path)
+	assert.Contains(t, files[1].Content, "//go:build linux\n")
+	assert.Contains(t, files[1].Content, `_ "github.com/nging-plugins/firewallmanager"`)
+	assert.True(t, isPluginImportFile(files[0].Path))
+
+	removeStalePluginImportFiles(files)
+	_, err = os.Stat(filepath.Join(root, `plugins_gen_windows.go`))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(root, `plugins_gen_custom.go`))
+	assert.NoError(t, err)
+
+	_, err = buildPluginImportFiles(map[string][]string{`linux`: nil, `unix`: nil})
+	assert.ErrorContains(t, err, `Plugins: VendorMiscDirs keys "linux" and "unix" overlap`)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/webx-top/com"
+)
+
+// 默认自动发现插件资源的模块路径前缀
+var defaultPluginModulePrefixes = []string{`github.com/nging-plugins/`}
+
+type pluginModule struct {
+	Path    string
+	Version string
+	Dir     string // 模块目录的绝对路径
+	Vendor  bool   // 是否位于 vendor 目录
+}
+
+// listVendorModules 解析 vendor/modules.txt
+func listVendorModules(projectPath string) ([]pluginModule, error) {
+	f, err := os.Open(filepath.Join(projectPath, `vendor`, `modules.txt`))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseVendorModules(f, projectPath)
+}
+
+func parseVendorModules(r io.Reader, projectPath string) ([]pluginModule, error) {
+	var modules []pluginModule
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, `# `) {
+			continue
+		}
+		// # github.com/nging-plugins/collector v1.2.3 [=> ../collector]
+		fields := strings.Fields(strings.TrimPrefix(line, `# `))
+		if len(fields) == 0 {
+			continue
+		}
+		m := pluginModule{
+			Path:   fields[0],
+			Dir:    filepath.Join(projectPath, `vendor`, filepath.FromSlash(fields[0])),
+			Vendor: true,
+		}
+		if len(fields) > 1 && fields[1] != `=>` {
+			m.Version = fields[1]
+		}
+		modules = append(modules, m)
+	}
+	return modules, s.Err()
+}
+
+// listGoModules 通过 `go list -m -json all` 获取依赖模块
+func listGoModules(ctx context.Context, projectPath string) ([]pluginModule, error) {
+	cmd := exec.CommandContext(ctx, `go`, `list`, `-m`, `-json`, `all`)
+	cmd.Dir = projectPath
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var modules []pluginModule
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var m struct {
+			Path    string
+			Version string
+			Dir     string
+			Replace *struct {
+				Path    string
+				Version string
+				Dir     string
+			}
+		}
+		err = dec.Decode(&m)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		module := pluginModule{Path: m.Path, Version: m.Version, Dir: m.Dir}
+		if m.Replace != nil && len(m.Replace.Dir) > 0 {
+			module.Dir = m.Replace.Dir
+		}
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
+
+// discoverPluginModules 查找模块路径以指定前缀开头的插件模块。优先读取 vendor/modules.txt
+func discoverPluginModules(ctx context.Context, projectPath string, prefixes []string) ([]pluginModule, error) {
+	modules, err := listVendorModules(projectPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		modules, err = listGoModules(ctx, projectPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return slices.DeleteFunc(modules, func(m pluginModule) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(m.Path, prefix) {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
+
+// pluginSupportedOS 根据插件根目录 Go 文件的构建约束(文件名后缀及 //go:build)判断插件支持的操作系统。
+// 所有操作系统下均没有可编译的 Go 文件时，视为支持所有操作系统
+func pluginSupportedOS(dir string, osArches map[string]string, buildTags []string) []string {
+	var supported []string
+	for osName, archName := range osArches {
+		ctx := build.Default
+		ctx.GOOS = osName
+		ctx.GOARCH = strings.SplitN(archName, `-`, 2)[0]
+		ctx.CgoEnabled = true
+		ctx.BuildTags = buildTags
+		_, err := ctx.ImportDir(dir, build.ImportComment)
+		var noGoErr *build.NoGoError
+		if errors.As(err, &noGoErr) {
+			continue
+		}
+		supported = append(supported, osName)
+	}
+	if len(supported) == 0 {
+		for osName := range osArches {
+			supported = append(supported, osName)
+		}
+	}
+	slices.Sort(supported)
+	return supported
+}
+
+// registryOSArches 返回目标注册表中的每个操作系统及其任意一个架构
+func registryOSArches() map[string]string {
+	osArches := map[string]string{}
+	for _, t := range getTargetRegistry() {
+		if _, ok := osArches[t.OS]; !ok {
+			osArches[t.OS] = t.Arch
+		}
+	}
+	return osArches
+}
+
+// matchMiscDirKey VendorMiscDirs 的 key(构建约束表达式)在该操作系统的任意架构下是否成立
+func matchMiscDirKey(key string, osName string, buildTags []string) bool {
+	if key == `*` {
+		return true
+	}
+	expr, err := parseMiscDirKey(key)
+	if err != nil {
+		return false
+	}
+	for _, t := range getTargetRegistry() {
+		if t.OS == osName && evalMiscDirKey(expr, t.OS, t.Arch, buildTags) {
+			return true
+		}
+	}
+	return false
+}
+
+// pluginAssetMiscDirs 返回插件中存在的资源目录(VendorMiscDirs 格式)
+func pluginAssetMiscDirs(m pluginModule) []string {
+	var dirs []string
+	for _, assetDir := range assetDirs() {
+		if !com.IsDir(filepath.Join(m.Dir, filepath.FromSlash(assetDir))) {
+			continue
+		}
+		if m.Vendor {
+			dirs = append(dirs, `vendor/`+m.Path+`/`+assetDir+`/`)
+		} else {
+			dirs = append(dirs, m.Path+`@/`+assetDir+`/`)
+		}
+	}
+	return dirs
+}
+
+// discoverPluginMiscDirs 自动发现插件的资源目录，并按 VendorMiscDirs 的 key 归类。
+// 插件支持的操作系统没有对应的 key 时，为这些操作系统添加新的 key(例如: freebsd || linux)。
+// 配置了 Plugins 时只打包已配置插件的资源，不再自动发现
+func discoverPluginMiscDirs(ctx context.Context) (map[string][]string, error) {
+	result := map[string][]string{}
+	if len(p.PluginModulePrefixes) == 0 || len(configuredPluginPaths()) > 0 {
+		return result, nil
+	}
+	modules, err := discoverPluginModules(ctx, p.ProjectPath, p.PluginModulePrefixes)
+	if err != nil {
+		return nil, err
+	}
+	osArches := registryOSArches()
+	for _, m := range modules {
+		if len(m.Dir) == 0 {
+			fmt.Println(`Warning		:	 skip plugin (not downloaded):`, m.Path)
+			continue
+		}
+		dirs := pluginAssetMiscDirs(m)
+		if len(dirs) == 0 {
+			continue
+		}
+		supported := pluginSupportedOS(m.Dir, osArches, p.BuildTags)
+		if len(supported) == len(osArches) {
+			fmt.Println(`[plugin]	:	`, m.Path, `(*)`)
+			result[`*`] = append(result[`*`], dirs...)
+			continue
+		}
+		fmt.Println(`[plugin]	:	`, m.Path, supported)
+		keys := pluginMiscDirKeys(result)
+		for _, key := range keys {
+			if slices.ContainsFunc(supported, func(osName string) bool {
+				return matchMiscDirKey(key, osName, p.BuildTags)
+			}) {
+				result[key] = append(result[key], dirs...)
+			}
+		}
+		uncovered := slices.DeleteFunc(slices.Clone(supported), func(osName string) bool {
+			return slices.ContainsFunc(keys, func(key string) bool {
+				return matchMiscDirKey(key, osName, p.BuildTags)
+			})
+		})
+		if len(uncovered) > 0 {
+			key := strings.Join(uncovered, ` || `)
+			fmt.Printf("Warning		:	 no VendorMiscDirs key matches plugin %s on %s, add key %q\n", m.Path, strings.Join(uncovered, `, `), key)
+			result[key] = append(result[key], dirs...)
+		}
+	}
+	return result, nil
+}
+
+// pluginMiscDirKeys 返回 VendorMiscDirs 及已自动添加的 key(不包括 `*`)
+func pluginMiscDirKeys(discovered map[string][]string) []string {
+	keys := miscDirKeys(p.VendorMiscDirs)
+	for _, key := range miscDirKeys(discovered) {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// mergeMiscDirs 合并资源目录(忽略重复项)
+func mergeMiscDirs(dst map[string][]string, src map[string][]string) map[string][]string {
+	merged := map[string][]string{}
+	for key, dirs := range dst {
+		merged[key] = slices.Clone(dirs)
+	}
+	for key, dirs := range src {
+		for _, dir := range dirs {
+			if !slices.ContainsFunc(merged[key], func(v string) bool {
+				return strings.TrimSuffix(v, `/`) == strings.TrimSuffix(dir, `/`)
+			}) {
+				merged[key] = append(merged[key], dir)
+			}
+		}
+	}
+	return merged
+}
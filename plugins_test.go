@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVendorModules(t *testing.T) {
+	modules, err := parseVendorModules(strings.NewReader(`# github.com/admpub/confl v0.2.4
+## explicit; go 1.21
+github.com/admpub/confl
+# github.com/nging-plugins/collector v1.2.3
+## explicit
+github.com/nging-plugins/collector
+# github.com/nging-plugins/firewallmanager => ../firewallmanager
+github.com/nging-plugins/firewallmanager
+`), `/project`)
+	assert.NoError(t, err)
+	assert.Len(t, modules, 3)
+	assert.Equal(t, `github.com/nging-plugins/collector`, modules[1].Path)
+	assert.Equal(t, `v1.2.3`, modules[1].Version)
+	assert.Equal(t, filepath.Join(`/project`, `vendor`, `github.com`, `nging-plugins`, `collector`), modules[1].Dir)
+	assert.Equal(t, ``, modules[2].Version)
+}
+
+func TestPluginSupportedOS(t *testing.T) {
+	osArches := map[string]string{`linux`: `amd64`, `darwin`: `arm64`, `windows`: `amd64`}
+	dir := t.TempDir()
+	assert.Equal(t, []string{`darwin`, `linux`, `windows`}, pluginSupportedOS(dir, osArches, nil))
+
+	err := os.WriteFile(filepath.Join(dir, `plugin.go`), []byte("//go:build linux\n\npackage plugin\n"), 0666)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`linux`}, pluginSupportedOS(dir, osArches, nil))
+
+	err = os.WriteFile(filepath.Join(dir, `plugin_windows.go`), []byte("package plugin\n"), 0666)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`linux`, `windows`}, pluginSupportedOS(dir, osArches, nil))
+
+	assert.True(t, matchMiscDirKey(`!linux`, `windows`, nil))
+	assert.False(t, matchMiscDirKey(`!linux`, `linux`, nil))
+	assert.True(t, matchMiscDirKey(`linux && (amd64 || arm64)`, `linux`, nil))
+	assert.False(t, matchMiscDirKey(`linux && db_mysql`, `linux`, nil))
+	assert.True(t, matchMiscDirKey(`linux && db_mysql`, `linux`, []string{`db_mysql`}))
+}
+
+func TestDiscoverPluginMiscDirs(t *testing.T) {
+	original := p.Clone()
+	defer func() {
+		p = original
+	}()
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`vendor/modules.txt`: "# github.com/nging-plugins/collector v1.0.0\ngithub.com/nging-plugins/collector\n" +
+			"# github.com/nging-plugins/firewallmanager v1.0.0\ngithub.com/nging-plugins/firewallmanager\n" +
+			"# github.com/nging-plugins/ddnsmanager v1.0.0\ngithub.com/nging-plugins/ddnsmanager\n",
+		`vendor/github.com/nging-plugins/collector/plugin.go`:                 "package collector\n",
+		`vendor/github.com/nging-plugins/collector/template/index.html`:       `collector`,
+		`vendor/github.com/nging-plugins/firewallmanager/plugin.go`:           "//go:build freebsd\n\npackage firewallmanager\n",
+		`vendor/github.com/nging-plugins/firewallmanager/template/index.html`: `firewall`,
+		`vendor/github.com/nging-plugins/ddnsmanager/plugin.go`:               "//go:build freebsd || netbsd\n\npackage ddnsmanager\n",
+		`vendor/github.com/nging-plugins/ddnsmanager/template/index.html`:     `ddns`,
+	})
+	p.ProjectPath = root
+	p.AssetDirs = nil
+	p.Plugins = nil
+	p.BuildTags = nil
+	p.PluginModulePrefixes = defaultPluginModulePrefixes
+	// 只有 `*` 时，仅支持部分操作系统的插件也不能被忽略
+	p.VendorMiscDirs = map[string][]string{`*`: {}}
+	discovered, err := discoverPluginMiscDirs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		`*`:       {`vendor/github.com/nging-plugins/collector/template/`},
+		`freebsd`: {`vendor/github.com/nging-plugins/firewallmanager/template/`, `vendor/github.com/nging-plugins/ddnsmanager/template/`},
+		`netbsd`:  {`vendor/github.com/nging-plugins/ddnsmanager/template/`},
+	}, discovered)
+	assert.NoError(t, checkMiscDirKeys(miscDirKeys(discovered), nil))
+
+	// 已有的 key 满足时不再添加
+	p.VendorMiscDirs = map[string][]string{`*`: {}, `linux`: {}, `!linux`: {}}
+	discovered, err = discoverPluginMiscDirs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`!linux`}, miscDirKeys(discovered))
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd.Process.Pid)
+	}
+}
+
+// killProcessGroup 先发送 SIGTERM(docker 客户端会将其转发给容器)，进程组在 commandKillDelay 内没有退出时发送 SIGKILL
+func killProcessGroup(pgid int) error {
+	err := unix.Kill(-pgid, unix.SIGTERM)
+	if err != nil {
+		if errors.Is(err, unix.ESRCH) {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+	deadline := time.Now().Add(commandKillDelay)
+	for time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		if unix.Kill(-pgid, 0) != nil {
+			return nil
+		}
+	}
+	err = unix.Kill(-pgid, unix.SIGKILL)
+	if errors.Is(err, unix.ESRCH) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestCommandKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), `pid`)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// shell 启动的子进程也要被结束
+	cmd := newCommand(ctx, `sh`, `-c`, `sleep 30 & echo $! > `+pidFile+`; wait`)
+	start := time.Now()
+	assert.Error(t, cmd.Run())
+	assert.Less(t, time.Since(start), 5*time.Second)
+	b, err := os.ReadFile(pidFile)
+	assert.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, unix.Kill(pid, 0), unix.ESRCH)
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+	cmd.Cancel = func() error {
+		// 结束整个进程树
+		err := exec.Command(`taskkill`, `/T`, `/F`, `/PID`, strconv.Itoa(cmd.Process.Pid)).Run()
+		if err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+}
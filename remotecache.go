@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RemoteCacheConfig 远程构建缓存配置。
+// 与 Bazel 的 HTTP 远程缓存类似: GET/PUT {URL}/ac/{构建缓存 key} 存取文件清单，GET/PUT {URL}/cas/{sha256} 存取文件内容
+type RemoteCacheConfig struct {
+	URL      string            // 远程缓存地址，为空时不使用远程缓存
+	Token    string            // Bearer 认证令牌，支持 ${ENV} 格式的环境变量
+	Username string            // Basic 认证用户名，支持 ${ENV} 格式的环境变量
+	Password string            // Basic 认证密码，支持 ${ENV} 格式的环境变量
+	Headers  map[string]string // 其它请求头，值支持 ${ENV} 格式的环境变量
+	ReadOnly bool              // 只下载不上传(例如开发者本地构建)
+	Timeout  int               // 每个请求的超时时间(秒)，默认: 300
+}
+
+func (c RemoteCacheConfig) Clone() RemoteCacheConfig {
+	r := c
+	r.Headers = map[string]string{}
+	for k, v := range c.Headers {
+		r.Headers[k] = v
+	}
+	return r
+}
+
+// remoteFile 远程缓存清单中的文件
+type remoteFile struct {
+	Name   string      `json:"name"`
+	SHA256 string      `json:"sha256"`
+	Size   int64       `json:"size"`
+	Mode   os.FileMode `json:"mode"`
+}
+
+// remoteManifest 远程缓存清单
+type remoteManifest struct {
+	Files []remoteFile `json:"files"`
+}
+
+type remoteCache struct {
+	cfg    RemoteCacheConfig
+	client *http.Client
+}
+
+// newRemoteCache 未配置 URL 时返回 nil
+func newRemoteCache(cfg RemoteCacheConfig) *remoteCache {
+	if len(cfg.URL) == 0 {
+		return nil
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 300 * time.Second
+	}
+	return &remoteCache{
+		cfg:    cfg,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (r *remoteCache) newRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(r.cfg.URL, `/`)+`/`+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.cfg.Token) > 0 {
+		req.Header.Set(`Authorization`, `Bearer `+os.ExpandEnv(r.cfg.Token))
+	} else if len(r.cfg.Username) > 0 {
+		req.SetBasicAuth(os.ExpandEnv(r.cfg.Username), os.ExpandEnv(r.cfg.Password))
+	}
+	for k, v := range r.cfg.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	return req, nil
+}
+
+func (r *remoteCache) do(req *http.Request) (*http.Response, error) {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return resp, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf(`remote cache: %s %s: %s`, req.Method, req.URL.Redacted(), resp.Status)
+	}
+	return resp, nil
+}
+
+func (r *remoteCache) put(req *http.Request) error {
+	resp, err := r.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf(`remote cache: %s %s: %s`, req.Method, req.URL.Redacted(), resp.Status)
+	}
+	return nil
+}
+
+// fetch 下载构建缓存 key 对应的文件到 destDir，并校验 sha256。缓存不存在时返回 false
+func (r *remoteCache) fetch(ctx context.Context, key string, destDir string) (bool, error) {
+	req, err := r.newRequest(ctx, http.MethodGet, `ac/`+key, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := r.do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	var manifest remoteManifest
+	if err = json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return false, fmt.Errorf(`remote cache: invalid manifest %s: %w`, key, err)
+	}
+	if len(manifest.Files) == 0 {
+		return false, nil
+	}
+	for _, file := range manifest.Files {
+		if err = r.fetchFile(ctx, file, destDir); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// validSHA256 是否为 64 位十六进制的 sha256 值
+func validSHA256(sum string) bool {
+	if len(sum) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(sum)
+	return err == nil
+}
+
+func (r *remoteCache) fetchFile(ctx context.Context, file remoteFile, destDir string) error {
+	name := filepath.Base(filepath.FromSlash(file.Name))
+	if name != file.Name || name == `.` || name == `..` || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf(`remote cache: invalid file name %q`, file.Name)
+	}
+	if !validSHA256(file.SHA256) {
+		return fmt.Errorf(`remote cache: invalid sha256 %q for %s`, file.SHA256, file.Name)
+	}
+	req, err := r.newRequest(ctx, http.MethodGet, `cas/`+file.SHA256, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf(`remote cache: missing content %s for %s`, file.SHA256, file.Name)
+	}
+	dest := filepath.Join(destDir, name)
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, file.Mode.Perm())
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != file.SHA256 || n != file.Size {
+		os.Remove(dest)
+		return fmt.Errorf(`remote cache: integrity check failed for %s: expected sha256 %s (%d bytes), got %s (%d bytes)`, file.Name, file.SHA256, file.Size, sum, n)
+	}
+	return os.Chmod(dest, file.Mode.Perm())
+}
+
+// upload 上传 dir 中的文件及其清单
+func (r *remoteCache) upload(ctx context.Context, key string, dir string, files []string) error {
+	if r.cfg.ReadOnly {
+		return nil
+	}
+	manifest := remoteManifest{Files: make([]remoteFile, 0, len(files))}
+	for _, name := range files {
+		file := filepath.Join(dir, name)
+		sum, err := sha256file(file)
+		if err != nil {
+			return err
+		}
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if err = r.uploadFile(ctx, sum, file, fi.Size()); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, remoteFile{Name: name, SHA256: sum, Size: fi.Size(), Mode: fi.Mode().Perm()})
+	}
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	req, err := r.newRequest(ctx, http.MethodPut, `ac/`+key, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(`Content-Type`, `application/json`)
+	return r.put(req)
+}
+
+// uploadFile 上传文件内容，远程已存在时跳过
+func (r *remoteCache) uploadFile(ctx context.Context, sum string, file string, size int64) error {
+	req, err := r.newRequest(ctx, http.MethodHead, `cas/`+sum, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		return nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	req, err = r.newRequest(ctx, http.MethodPut, `cas/`+sum, f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set(`Content-Type`, `application/octet-stream`)
+	return r.put(req)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCacheServer 内存中的 HTTP 远程缓存
+func newTestCacheServer(t *testing.T, token string) (*httptest.Server, map[string][]byte) {
+	var mu sync.Mutex
+	entries := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(`Authorization`) != `Bearer `+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, `/`)
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			b, ok := entries[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if r.Method == http.MethodGet {
+				w.Write(b)
+			}
+		case http.MethodPut:
+			b, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			entries[key] = b
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, entries
+}
+
+func TestRemoteCache(t *testing.T) {
+	t.Setenv(`TEST_CACHE_TOKEN`, `secret`)
+	srv, entries := newTestCacheServer(t, `secret`)
+	cfg := RemoteCacheConfig{URL: srv.URL + `/`, Token: `${TEST_CACHE_TOKEN}`}
+	ctx := context.Background()
+	key1, key2 := strings.Repeat(`1`, 64), strings.Repeat(`2`, 64)
+
+	releaseDir := t.TempDir()
+	writeTestFiles(t, releaseDir, map[string]string{
+		`nging-linux-amd64`: `binary`,
+		`startup`:           `startup`,
+	})
+	assert.NoError(t, os.Chmod(filepath.Join(releaseDir, `nging-linux-amd64`), 0755))
+	local := newBuildCache(t.TempDir(), nil)
+	local.remote = newRemoteCache(cfg)
+	assert.NoError(t, local.store(ctx, key1, releaseDir, []string{`nging-linux-amd64`, `startup`}))
+	assert.Contains(t, entries, `ac/`+key1)
+	assert.Len(t, entries, 3)
+
+	// 其它机器: 本地缓存为空时从远程缓存下载
+	other := newBuildCache(t.TempDir(), nil)
+	other.remote = newRemoteCache(cfg)
+	restoreDir := t.TempDir()
+	ok, err := other.restore(ctx, key1, restoreDir)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	b, err := os.ReadFile(filepath.Join(restoreDir, `nging-linux-amd64`))
+	assert.NoError(t, err)
+	assert.Equal(t, `binary`, string(b))
+	fi, err := os.Stat(filepath.Join(restoreDir, `nging-linux-amd64`))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+	assert.DirExists(t, filepath.Join(other.dir, `build`, key1))
+
+	ok, err = other.restore(ctx, `missing`, t.TempDir())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// 内容被篡改
+	for key := range entries {
+		if strings.HasPrefix(key, `cas/`) {
+			entries[key] = []byte(`tampered`)
+		}
+	}
+	third := newBuildCache(t.TempDir(), nil)
+	third.remote = newRemoteCache(cfg)
+	_, err = third.remote.fetch(ctx, key1, t.TempDir())
+	assert.ErrorContains(t, err, `integrity check failed`)
+	// 远程缓存出错时不中断构建
+	ok, err = third.restore(ctx, key1, t.TempDir())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoDirExists(t, filepath.Join(third.dir, `build`, key1))
+
+	// 非法的清单
+	for name, manifest := range map[string]string{
+		`invalid file name`: `{"files":[{"name":"..","sha256":"` + strings.Repeat(`a`, 64) + `","size":1}]}`,
+		`invalid sha256`:    `{"files":[{"name":"nging","sha256":"../../ac/x","size":1}]}`,
+	} {
+		entries[`ac/`+key2] = []byte(manifest)
+		_, err = third.remote.fetch(ctx, key2, t.TempDir())
+		assert.ErrorContains(t, err, name)
+	}
+	delete(entries, `ac/`+key2)
+
+	// 认证失败
+	unauthorized := newRemoteCache(RemoteCacheConfig{URL: srv.URL, Token: `wrong`})
+	_, err = unauthorized.fetch(ctx, key1, t.TempDir())
+	assert.ErrorContains(t, err, `401 Unauthorized`)
+
+	// 只读
+	readOnly := newBuildCache(t.TempDir(), nil)
+	readOnly.remote = newRemoteCache(RemoteCacheConfig{URL: srv.URL, Token: `secret`, ReadOnly: true})
+	assert.NoError(t, readOnly.store(ctx, key2, releaseDir, []string{`startup`}))
+	assert.NotContains(t, entries, `ac/`+key2)
+
+	assert.Nil(t, newRemoteCache(RemoteCacheConfig{}))
+}
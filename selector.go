@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+)
+
+// 代表本机 GOOS/GOARCH 的目标关键字
+const hostTargetKeyword = `host`
+
+// 目标组允许的最大嵌套层数
+const maxTargetGroupDepth = 10
+
+type selectedTarget struct {
+	Target   string // ${GOOS}/${GOARCH}
+	Compiler string // 为空时采用默认编译器
+}
+
+// hostTarget 返回本机对应的目标
+func hostTarget() string {
+	arch := runtime.GOARCH
+	if arch == `arm` {
+		goarm := `7`
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range info.Settings {
+				if setting.Key == `GOARM` && len(setting.Value) > 0 {
+					goarm = strings.SplitN(setting.Value, `,`, 2)[0]
+				}
+			}
+		}
+		arch += `-` + goarm
+	}
+	return runtime.GOOS + `/` + arch
+}
+
+func isTargetGlob(s string) bool {
+	return strings.ContainsAny(s, `*?[`)
+}
+
+// knownTargets 返回目标注册表及配置文件 Targets 中的所有目标
+func knownTargets() []string {
+	registry := getTargetRegistry()
+	targets := make([]string, 0, len(registry)+len(p.Targets))
+	for _, t := range registry {
+		targets = append(targets, t.Target())
+	}
+	for _, target := range p.Targets {
+		if !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// expandTargetTerm 将单个目标(别名、${GOOS}/${GOARCH}、通配符、目标组或 host)展开为目标列表
+func expandTargetTerm(term string, depth int) ([]string, error) {
+	if depth > maxTargetGroupDepth {
+		return nil, fmt.Errorf(`target group nesting too deep: %q`, term)
+	}
+	if term == hostTargetKeyword {
+		return []string{hostTarget()}, nil
+	}
+	if exprs, ok := p.TargetGroups[term]; ok {
+		var targets []string
+		for _, expr := range exprs {
+			for _, v := range splitTargetExpr(expr) {
+				expanded, err := expandTargetTerm(v, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				targets = appendUniqueTargets(targets, expanded...)
+			}
+		}
+		return targets, nil
+	}
+	if isTargetGlob(term) {
+		var targets []string
+		for _, target := range knownTargets() {
+			if matchTarget(term, target) || matchTarget(term, targetName(target)) {
+				targets = append(targets, target)
+			}
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf(`no target matches %q`, term)
+		}
+		return targets, nil
+	}
+	if target := getTarget(term); len(target) > 0 {
+		return []string{target}, nil
+	}
+	return nil, fmt.Errorf(`unsupported target %q`, term)
+}
+
+func splitTargetExpr(expr string) []string {
+	var terms []string
+	for _, term := range strings.Split(expr, `,`) {
+		term = strings.TrimSpace(term)
+		if len(term) > 0 {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func appendUniqueTargets(targets []string, items ...string) []string {
+	for _, item := range items {
+		if !slices.Contains(targets, item) {
+			targets = append(targets, item)
+		}
+	}
+	return targets
+}
+
+// parseTargetExpr 解析以逗号分隔的目标表达式。
+// 每一项可以是别名(linux_amd64)、${GOOS}/${GOARCH}(linux/amd64)、通配符(linux/*、*/arm64)、
+// 配置文件 TargetGroups 中定义的目标组或 host 关键字，并可以添加 `编译器:` 前缀(例如 go:server)；
+// 以 `!` 开头的项表示排除。只有排除项时，从默认目标中排除。
+func parseTargetExpr(expr string) ([]selectedTarget, error) {
+	var includes []selectedTarget
+	var excludes []string
+	var hasInclude bool
+	for _, term := range splitTargetExpr(expr) {
+		if strings.HasPrefix(term, `!`) {
+			term = strings.TrimSpace(strings.TrimPrefix(term, `!`))
+			if parts := strings.SplitN(term, `:`, 2); len(parts) == 2 {
+				term = strings.TrimSpace(parts[1])
+			}
+			expanded, err := expandTargetTerm(term, 0)
+			if err != nil {
+				return nil, err
+			}
+			excludes = appendUniqueTargets(excludes, expanded...)
+			continue
+		}
+		hasInclude = true
+		var compiler string
+		if parts := strings.SplitN(term, `:`, 2); len(parts) == 2 {
+			compiler = strings.TrimSpace(parts[0])
+			term = strings.TrimSpace(parts[1])
+		}
+		expanded, err := expandTargetTerm(term, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range expanded {
+			index := slices.IndexFunc(includes, func(v selectedTarget) bool {
+				return v.Target == target
+			})
+			if index > -1 {
+				if len(compiler) > 0 {
+					includes[index].Compiler = compiler
+				}
+				continue
+			}
+			includes = append(includes, selectedTarget{Target: target, Compiler: compiler})
+		}
+	}
+	if !hasInclude {
+		defaults, err := getDefaultTargets()
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range defaults {
+			includes = append(includes, selectedTarget{Target: target})
+		}
+	}
+	return slices.DeleteFunc(includes, func(v selectedTarget) bool {
+		return slices.Contains(excludes, v.Target)
+	}), nil
+}
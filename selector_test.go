@@ -0,0 +1,57 @@
+package main
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTargetExpr(t *testing.T) {
+	// 使用内置的目标列表，测试结束后恢复注册表
+	registry, names := getTargetRegistry(), targetNames
+	t.Cleanup(func() {
+		targetRegistry, targetNames = registry, names
+		p.TargetGroups = nil
+	})
+	targetRegistry = buildTargetRegistry(builtinDistTargets, defaultTargets)
+	targetNames = maps.Clone(names)
+	for _, v := range targetRegistry {
+		targetNames[v.Name] = v.Target()
+	}
+	p.TargetGroups = map[string][]string{
+		`server`: {`linux/amd64,linux/arm64`},
+		`all`:    {`server`, `darwin/*`},
+	}
+
+	selected, err := parseTargetExpr(`linux/arm*,!linux_arm5`)
+	assert.NoError(t, err)
+	assert.Equal(t, []selectedTarget{
+		{Target: `linux/arm-6`},
+		{Target: `linux/arm-7`},
+		{Target: `linux/arm64`},
+	}, selected)
+
+	selected, err = parseTargetExpr(`go:all,!darwin/amd64`)
+	assert.NoError(t, err)
+	assert.Equal(t, []selectedTarget{
+		{Target: `linux/amd64`, Compiler: `go`},
+		{Target: `linux/arm64`, Compiler: `go`},
+		{Target: `darwin/arm64`, Compiler: `go`},
+	}, selected)
+
+	selected, err = parseTargetExpr(`*/arm64`)
+	assert.NoError(t, err)
+	assert.Len(t, selected, 4)
+
+	selected, err = parseTargetExpr(`host`)
+	assert.NoError(t, err)
+	assert.Equal(t, []selectedTarget{{Target: hostTarget()}}, selected)
+
+	selected, err = parseTargetExpr(`!windows_386`)
+	assert.NoError(t, err)
+	assert.Len(t, selected, len(defaultTargets)-1)
+
+	_, err = parseTargetExpr(`plan9/mips`)
+	assert.Error(t, err)
+}
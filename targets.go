@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/webx-top/com"
+)
+
+// xgo 支持的平台
+var (
+	xgoSupportedPlatforms    = []string{`darwin`, `linux`, `windows`}
+	xgoSupportedAchitectures = []string{`386`, `amd64`, `arm-5`, `arm-6`, `arm-7`, `arm64`, `mips`, `mipsle`, `mips64`, `mips64le`}
+)
+
+// 未指定目标时默认构建的目标
+var defaultTargets = []string{
+	`linux/386`,
+	`linux/amd64`,
+	`linux/arm-5`,
+	`linux/arm-6`,
+	`linux/arm-7`,
+	`linux/arm64`,
+	`linux/loong64`,
+	`linux/mips`,
+	`linux/mipsle`,
+	`linux/mips64`,
+	`linux/mips64le`,
+	`linux/riscv64`,
+	`darwin/amd64`,
+	`darwin/arm64`,
+	`windows/386`,
+	`windows/amd64`,
+	`windows/arm64`,
+	`freebsd/amd64`,
+	`freebsd/arm64`,
+}
+
+// armVersions 为 GOARCH=arm 展开的 GOARM 变体
+var armVersions = []string{`5`, `6`, `7`}
+
+// 架构变体对应的环境变量。目标格式: ${GOOS}/${GOARCH}-${变体}，例如: linux/amd64-v3、linux/arm64-v8.2、windows/386-softfloat
+var archVariantEnvNames = map[string]string{
+	`arm`:      `GOARM`,
+	`amd64`:    `GOAMD64`,
+	`arm64`:    `GOARM64`,
+	`386`:      `GO386`,
+	`mips`:     `GOMIPS`,
+	`mipsle`:   `GOMIPS`,
+	`mips64`:   `GOMIPS64`,
+	`mips64le`: `GOMIPS64`,
+	`ppc64`:    `GOPPC64`,
+	`ppc64le`:  `GOPPC64`,
+	`riscv64`:  `GORISCV64`,
+}
+
+var (
+	mipsVariantRegexp  = regexp.MustCompile(`^(hardfloat|softfloat)$`)
+	ppc64VariantRegexp = regexp.MustCompile(`^power(8|9|10)$`)
+)
+
+// 架构变体的合法取值
+var archVariantRegexps = map[string]*regexp.Regexp{
+	`arm`:      regexp.MustCompile(`^[5-7]$`),
+	`amd64`:    regexp.MustCompile(`^v[1-4]$`),
+	`arm64`:    regexp.MustCompile(`^v(8\.[0-9]|9\.[0-5])$`),
+	`386`:      regexp.MustCompile(`^(sse2|softfloat)$`),
+	`mips`:     mipsVariantRegexp,
+	`mipsle`:   mipsVariantRegexp,
+	`mips64`:   mipsVariantRegexp,
+	`mips64le`: mipsVariantRegexp,
+	`ppc64`:    ppc64VariantRegexp,
+	`ppc64le`:  ppc64VariantRegexp,
+	`riscv64`:  regexp.MustCompile(`^rva2[023]u64$`),
+}
+
+// isValidArchVariant 检查架构变体是否有效
+func isValidArchVariant(arch string, variant string) bool {
+	re, ok := archVariantRegexps[arch]
+	return ok && re.MatchString(variant)
+}
+
+// TargetInfo 构建目标信息
+type TargetInfo struct {
+	Name      string   // 别名，例如: linux_arm7
+	OS        string   // GOOS
+	Arch      string   // 含变体后缀的架构，例如: arm-7
+	Compilers []string // 支持的编译器
+	Cgo       bool     // 是否支持 cgo
+	Default   bool     // 未指定目标时是否默认构建
+}
+
+// Target 返回 ${GOOS}/${GOARCH}[-变体] 格式的目标
+func (t TargetInfo) Target() string {
+	return t.OS + `/` + t.Arch
+}
+
+// Variant 返回变体对应的环境变量，例如: GOARM=7
+func (t TargetInfo) Variant() string {
+	return strings.Join(archEnvVars(t.Arch)[1:], ` `)
+}
+
+// archEnvVars 将含变体后缀的架构转换为环境变量，例如: amd64-v3 => GOARCH=amd64 GOAMD64=v3
+func archEnvVars(arch string) []string {
+	parts := strings.SplitN(arch, `-`, 2)
+	env := []string{`GOARCH=` + parts[0]}
+	if len(parts) == 2 {
+		if name, ok := archVariantEnvNames[parts[0]]; ok {
+			env = append(env, name+`=`+parts[1])
+		}
+	}
+	return env
+}
+
+// targetName 生成目标别名，例如: linux/arm-7 => linux_arm7
+func targetName(target string) string {
+	return strings.NewReplacer(`/`, `_`, `-`, ``).Replace(target)
+}
+
+type distTarget struct {
+	GOOS         string
+	GOARCH       string
+	CgoSupported bool
+}
+
+// 无法执行 `go tool dist list` 时采用的平台列表
+var builtinDistTargets = []distTarget{
+	{GOOS: `darwin`, GOARCH: `amd64`, CgoSupported: true},
+	{GOOS: `darwin`, GOARCH: `arm64`, CgoSupported: true},
+	{GOOS: `freebsd`, GOARCH: `amd64`, CgoSupported: true},
+	{GOOS: `freebsd`, GOARCH: `arm64`, CgoSupported: true},
+	{GOOS: `linux`, GOARCH: `386`, CgoSupported: true},
+	{GOOS: `linux`, GOARCH: `amd64`, CgoSupported: true},
+	{GOOS: `linux`, GOARCH: `arm`, CgoSupported: true},
+	{GOOS: `linux`, GOARCH: `arm64`, CgoSupported: true},
+	{GOOS: `linux`, GOARCH: `loong64`, CgoSupported: true},
+	{GOOS: `linux`, GOARCH: `mips`, CgoSupported: true},
+	{GOOS: `linux`, GOARCH: `mips64`, CgoSupported: true},
+	{GOOS: `linux`, GOARCH: `mips64le`, CgoSupported: true},
+	{GOOS: `linux`, GOARCH: `mipsle`, CgoSupported: true},
+	{GOOS: `linux`, GOARCH: `riscv64`, CgoSupported: true},
+	{GOOS: `windows`, GOARCH: `386`, CgoSupported: true},
+	{GOOS: `windows`, GOARCH: `amd64`, CgoSupported: true},
+	{GOOS: `windows`, GOARCH: `arm64`, CgoSupported: true},
+}
+
+func execGoToolDistList(ctx context.Context) ([]distTarget, error) {
+	cmd := exec.CommandContext(ctx, `go`, `tool`, `dist`, `list`, `-json`)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var list []distTarget
+	err = json.Unmarshal(out, &list)
+	return list, err
+}
+
+// newTargetInfo 生成目标信息。go 支持所有目标，xgo 仅支持部分平台(除 GOARM 外不支持其它架构变体)
+func newTargetInfo(osName string, archName string, cgo bool) TargetInfo {
+	t := TargetInfo{
+		OS:        osName,
+		Arch:      archName,
+		Compilers: []string{compilerGo},
+		Cgo:       cgo,
+	}
+	t.Name = targetName(t.Target())
+	if com.InSlice(osName, xgoSupportedPlatforms) && com.InSlice(archName, xgoSupportedAchitectures) {
+		t.Compilers = append(t.Compilers, compilerXgo)
+	}
+	return t
+}
+
+func buildTargetRegistry(list []distTarget, defaults []string) []TargetInfo {
+	registry := make([]TargetInfo, 0, len(list)+len(armVersions))
+	for _, v := range list {
+		if v.GOARCH == `arm` {
+			for _, armVersion := range armVersions {
+				registry = append(registry, newTargetInfo(v.GOOS, v.GOARCH+`-`+armVersion, v.CgoSupported))
+			}
+			continue
+		}
+		registry = append(registry, newTargetInfo(v.GOOS, v.GOARCH, v.CgoSupported))
+	}
+	for index, t := range registry {
+		registry[index].Default = slices.Contains(defaults, t.Target())
+	}
+	return registry
+}
+
+var (
+	targetRegistry     []TargetInfo
+	targetRegistryOnce sync.Once
+)
+
+// getTargetRegistry 返回所有已知的构建目标
+func getTargetRegistry() []TargetInfo {
+	targetRegistryOnce.Do(func() {
+		list, err := execGoToolDistList(context.Background())
+		if err != nil || len(list) == 0 {
+			fmt.Println(`Warning		:	 failed to execute "go tool dist list", use builtin target list:`, err)
+			list = builtinDistTargets
+		}
+		targetRegistry = buildTargetRegistry(list, defaultTargetList())
+		for _, t := range targetRegistry {
+			if _, ok := targetNames[t.Name]; !ok {
+				targetNames[t.Name] = t.Target()
+			}
+		}
+	})
+	return targetRegistry
+}
+
+// lookupTargetInfo 查找目标信息。target 可以是别名或 ${GOOS}/${GOARCH}[-变体] 格式。
+// 注册表中没有的变体目标(例如 linux/amd64-v3)，只要基础目标存在且变体有效即可使用
+func lookupTargetInfo(target string) (TargetInfo, bool) {
+	if t, ok := targetNames[target]; ok {
+		target = t
+	}
+	for _, t := range getTargetRegistry() {
+		if t.Target() == target {
+			return t, true
+		}
+	}
+	osName, archName, _ := strings.Cut(target, `/`)
+	baseArch, variant, hasVariant := strings.Cut(archName, `-`)
+	if !hasVariant || !isValidArchVariant(baseArch, variant) {
+		return TargetInfo{}, false
+	}
+	for _, t := range getTargetRegistry() {
+		if t.OS == osName && t.Arch == baseArch {
+			return newTargetInfo(osName, archName, t.Cgo), true
+		}
+	}
+	return TargetInfo{}, false
+}
+
+func defaultTargetList() []string {
+	if len(p.DefaultTargets) > 0 {
+		return p.DefaultTargets
+	}
+	return defaultTargets
+}
+
+// getDefaultTargets 返回未指定目标时默认构建的目标(包括配置文件中 Targets 定义的目标)。
+// 内置默认目标中当前工具链不支持的目标会被跳过，配置文件 DefaultTargets 中的未知目标则返回错误
+func getDefaultTargets() ([]string, error) {
+	var targets []string
+	for _, target := range defaultTargetList() {
+		t, ok := lookupTargetInfo(target)
+		if !ok {
+			if len(p.DefaultTargets) > 0 {
+				return nil, fmt.Errorf(`DefaultTargets: unknown target %q`, target)
+			}
+			continue
+		}
+		if !slices.Contains(targets, t.Target()) {
+			targets = append(targets, t.Target())
+		}
+	}
+	for _, target := range p.Targets {
+		if !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// hostCompilers 返回本机可以用来构建该目标的编译器
+func hostCompilers(t TargetInfo) []string {
+	var compilers []string
+	for _, compiler := range t.Compilers {
+		if _, err := exec.LookPath(compiler); err != nil {
+			continue
+		}
+		if compiler == compilerGo && t.Cgo && isHostTarget(t.OS, t.Arch) {
+			compiler += `+cgo`
+		}
+		compilers = append(compilers, compiler)
+	}
+	return compilers
+}
+
+// listTargets 输出构建目标能力矩阵
+func listTargets(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tNAME\tCOMPILERS\tCGO\tVARIANT\tDEFAULT\tHOST")
+	for _, t := range getTargetRegistry() {
+		variant := t.Variant()
+		if len(variant) == 0 {
+			variant = `-`
+		}
+		host := strings.Join(hostCompilers(t), `,`)
+		if len(host) == 0 {
+			host = `-`
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%s\t%v\t%s\n",
+			t.Target(), t.Name, strings.Join(t.Compilers, `,`), t.Cgo, variant, t.Default, host)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTargetRegistry(t *testing.T) {
+	registry := buildTargetRegistry([]distTarget{
+		{GOOS: `linux`, GOARCH: `arm`, CgoSupported: true},
+		{GOOS: `freebsd`, GOARCH: `amd64`, CgoSupported: true},
+	}, []string{`linux/arm-7`})
+	assert.Len(t, registry, 4)
+	assert.Equal(t, `linux/arm-5`, registry[0].Target())
+	assert.Equal(t, `linux_arm7`, registry[2].Name)
+	assert.Equal(t, `GOARM=7`, registry[2].Variant())
+	assert.True(t, registry[2].Default)
+	assert.False(t, registry[0].Default)
+	assert.Equal(t, []string{`go`, `xgo`}, registry[2].Compilers)
+	assert.Equal(t, []string{`go`}, registry[3].Compilers)
+	assert.Equal(t, ``, registry[3].Variant())
+}
+
+func TestArchVariant(t *testing.T) {
+	assert.Equal(t, []string{`GOARCH=amd64`, `GOAMD64=v3`}, archEnvVars(`amd64-v3`))
+	assert.Equal(t, []string{`GOARCH=arm64`, `GOARM64=v8.2`}, archEnvVars(`arm64-v8.2`))
+	assert.Equal(t, []string{`GOARCH=386`, `GO386=softfloat`}, archEnvVars(`386-softfloat`))
+	assert.Equal(t, []string{`GOARCH=arm`, `GOARM=7`}, archEnvVars(`arm-7`))
+	assert.True(t, isValidArchVariant(`arm64`, `v9.1`))
+	assert.False(t, isValidArchVariant(`amd64`, `v5`))
+
+	info, ok := lookupTargetInfo(`linux/amd64-v3`)
+	assert.True(t, ok)
+	assert.Equal(t, `linux_amd64v3`, info.Name)
+	assert.Equal(t, `GOAMD64=v3`, info.Variant())
+	assert.Equal(t, []string{`go`}, info.Compilers)
+	_, ok = lookupTargetInfo(`linux/amd64-v9`)
+	assert.False(t, ok)
+}
+
+func TestGetDefaultTargets(t *testing.T) {
+	original := p.Clone()
+	defer func() {
+		p = original
+	}()
+	p.DefaultTargets = nil
+	p.Targets = nil
+	targets, err := getDefaultTargets()
+	assert.NoError(t, err)
+	assert.Contains(t, targets, `linux/riscv64`)
+	assert.Contains(t, targets, `freebsd/amd64`)
+
+	p.DefaultTargets = []string{`linux_amd64`, `freebsd/amd64`, `linux/amd64`}
+	targets, err = getDefaultTargets()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`linux/amd64`, `freebsd/amd64`}, targets)
+
+	p.DefaultTargets = []string{`linux_amd64`, `linux_amd46`}
+	_, err = getDefaultTargets()
+	assert.EqualError(t, err, `DefaultTargets: unknown target "linux_amd46"`)
+}
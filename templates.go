@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// TemplateCheckConfig 模板语法校验配置
+type TemplateCheckConfig struct {
+	Validate   bool     // 生成 go:generate 文件前校验模板语法
+	Strict     bool     // 存在语法错误时中止构建
+	Dir        string   // 模板目录(相对于项目目录或插件目录)，默认: template
+	Extensions []string // 模板文件扩展名，默认: .html、.htm、.tpl
+	Ignore     string   // 忽略的模板文件(正则表达式，匹配相对于模板目录的路径)
+	LeftDelim  string   // 左定界符，默认: {{
+	RightDelim string   // 右定界符，默认: }}
+	Funcs      []string // 模板函数名称(校验时使用空实现)。为空时不检查函数是否已定义
+}
+
+// 默认的模板语法校验配置
+var defaultTemplateCheckConfig = TemplateCheckConfig{
+	Dir:        `template`,
+	Extensions: []string{`.html`, `.htm`, `.tpl`},
+	LeftDelim:  `{{`,
+	RightDelim: `}}`,
+}
+
+func (c TemplateCheckConfig) Clone() TemplateCheckConfig {
+	r := c
+	r.Extensions = make([]string, len(c.Extensions))
+	copy(r.Extensions, c.Extensions)
+	r.Funcs = make([]string, len(c.Funcs))
+	copy(r.Funcs, c.Funcs)
+	return r
+}
+
+// WithDefaults 为未设置的项填充默认值
+func (c TemplateCheckConfig) WithDefaults() TemplateCheckConfig {
+	r := c.Clone()
+	if len(r.Dir) == 0 {
+		r.Dir = defaultTemplateCheckConfig.Dir
+	}
+	r.Dir = strings.Trim(path.Clean(r.Dir), `/`)
+	if len(r.Extensions) == 0 {
+		r.Extensions = defaultTemplateCheckConfig.Extensions
+	}
+	if len(r.LeftDelim) == 0 {
+		r.LeftDelim = defaultTemplateCheckConfig.LeftDelim
+	}
+	if len(r.RightDelim) == 0 {
+		r.RightDelim = defaultTemplateCheckConfig.RightDelim
+	}
+	return r
+}
+
+// templateIssue 模板语法错误
+type templateIssue struct {
+	File    string // 模板文件路径
+	Line    int    // 行号，未知时为 0
+	Message string
+}
+
+func (t templateIssue) String() string {
+	if t.Line > 0 {
+		return t.File + `:` + strconv.Itoa(t.Line) + `: ` + t.Message
+	}
+	return t.File + `: ` + t.Message
+}
+
+var templateErrorRegexp = regexp.MustCompile(`^template: (.*?):([0-9]+):(?:[0-9]+:)? (.*)$`)
+
+// newTemplateIssue 从模板解析错误中提取行号
+func newTemplateIssue(file string, err error) templateIssue {
+	msg := err.Error()
+	if matches := templateErrorRegexp.FindStringSubmatch(msg); len(matches) == 4 {
+		line, _ := strconv.Atoi(matches[2])
+		return templateIssue{File: file, Line: line, Message: matches[3]}
+	}
+	return templateIssue{File: file, Message: strings.TrimPrefix(msg, `template: `)}
+}
+
+func stubTemplateFunc(...interface{}) interface{} {
+	return nil
+}
+
+// collectTemplateFuncs 收集模板中调用的函数名称
+func collectTemplateFuncs(node parse.Node, funcs map[string]struct{}) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectTemplateFuncs(child, funcs)
+		}
+	case *parse.ActionNode:
+		collectTemplateFuncs(n.Pipe, funcs)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectTemplateFuncs(cmd, funcs)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectTemplateFuncs(arg, funcs)
+		}
+	case *parse.ChainNode:
+		collectTemplateFuncs(n.Node, funcs)
+	case *parse.IdentifierNode:
+		funcs[n.Ident] = struct{}{}
+	case *parse.IfNode:
+		collectTemplateFuncs(&n.BranchNode, funcs)
+	case *parse.RangeNode:
+		collectTemplateFuncs(&n.BranchNode, funcs)
+	case *parse.WithNode:
+		collectTemplateFuncs(&n.BranchNode, funcs)
+	case *parse.BranchNode:
+		collectTemplateFuncs(n.Pipe, funcs)
+		collectTemplateFuncs(n.List, funcs)
+		collectTemplateFuncs(n.ElseList, funcs)
+	case *parse.TemplateNode:
+		collectTemplateFuncs(n.Pipe, funcs)
+	}
+}
+
+// templateFuncMap 返回校验时使用的函数(空实现)。未配置 Funcs 时使用模板中调用的所有函数
+func templateFuncMap(name string, text string, cfg TemplateCheckConfig) (template.FuncMap, error) {
+	funcMap := template.FuncMap{}
+	if len(cfg.Funcs) > 0 {
+		for _, fn := range cfg.Funcs {
+			funcMap[fn] = stubTemplateFunc
+		}
+		return funcMap, nil
+	}
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	treeSet := map[string]*parse.Tree{}
+	_, err := tree.Parse(text, cfg.LeftDelim, cfg.RightDelim, treeSet)
+	if err != nil {
+		return nil, err
+	}
+	funcs := map[string]struct{}{}
+	for _, t := range treeSet {
+		collectTemplateFuncs(t.Root, funcs)
+	}
+	for fn := range funcs {
+		funcMap[fn] = stubTemplateFunc
+	}
+	return funcMap, nil
+}
+
+// validateTemplateFile 使用 html/template 解析模板文件
+func validateTemplateFile(file string, displayName string, cfg TemplateCheckConfig) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	text := string(b)
+	funcMap, err := templateFuncMap(displayName, text, cfg)
+	if err != nil {
+		return err
+	}
+	_, err = template.New(displayName).Delims(cfg.LeftDelim, cfg.RightDelim).Funcs(funcMap).Parse(text)
+	return err
+}
+
+// validateTemplateDir 校验模板目录中的所有模板文件
+func validateTemplateDir(root string, displayDir string, cfg TemplateCheckConfig) ([]templateIssue, error) {
+	var ignore *regexp.Regexp
+	if len(cfg.Ignore) > 0 {
+		var err error
+		ignore, err = regexp.Compile(cfg.Ignore)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var issues []templateIssue
+	err := filepath.WalkDir(root, func(fpath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if fpath == root && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || !slices.Contains(cfg.Extensions, strings.ToLower(filepath.Ext(fpath))) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, fpath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if ignore != nil && ignore.MatchString(rel) {
+			return nil
+		}
+		display := path.Join(displayDir, rel)
+		if err := validateTemplateFile(fpath, display, cfg); err != nil {
+			issues = append(issues, newTemplateIssue(display, err))
+		}
+		return nil
+	})
+	return issues, err
+}
+
+// checkTemplates 校验所有模板目录并输出语法错误，返回是否没有错误
+func checkTemplates(ctx context.Context, vendorMiscDirs map[string][]string, w io.Writer) (bool, error) {
+	cfg := p.TemplateCheck.WithDefaults()
+	valid := true
+	for _, dir := range miscDirsNamed(ctx, vendorMiscDirs, cfg.Dir) {
+		root := filepath.FromSlash(dir)
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(p.ProjectPath, root)
+		}
+		issues, err := validateTemplateDir(root, dir, cfg)
+		if err != nil {
+			return false, err
+		}
+		for _, issue := range issues {
+			fmt.Fprintln(w, `[template]	:	`, issue.String())
+		}
+		if len(issues) > 0 {
+			valid = false
+		}
+	}
+	return valid, nil
+}
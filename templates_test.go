@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTemplateDir(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`index.html`:         "{{define \"main\"}}\n{{T \"hello\"|ToHTML}}\n{{if .user}}{{.user.Name}}{{end}}\n{{end}}\n",
+		`broken.html`:        "<p>\n{{if .user}}\n{{.user.Name}\n{{end}}\n",
+		`unclosed.tpl`:       "{{range .list}}\n{{.}}\n",
+		`delims.html`:        "{{ .ignored }\n",
+		`script.js`:          "{{ not a template",
+		`ignored/skip.html`:  "{{",
+		`common/header.html`: "{{Include \"common/nav\"}}\n",
+	})
+	cfg := defaultTemplateCheckConfig.WithDefaults()
+	cfg.Ignore = `^ignored/`
+	issues, err := validateTemplateDir(root, `template`, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []templateIssue{
+		{File: `template/broken.html`, Line: 3, Message: `bad character U+007D '}'`},
+		{File: `template/delims.html`, Line: 1, Message: `unexpected "}" in operand`},
+		{File: `template/unclosed.tpl`, Line: 3, Message: `unexpected EOF`},
+	}, issues)
+	assert.Equal(t, `template/broken.html:3: bad character U+007D '}'`, issues[0].String())
+
+	cfg.LeftDelim, cfg.RightDelim = `{%`, `%}`
+	issues, err = validateTemplateDir(root, `template`, cfg)
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+
+	cfg = defaultTemplateCheckConfig.WithDefaults()
+	cfg.Ignore = `^(ignored/|broken|delims|unclosed)`
+	cfg.Funcs = []string{`T`, `ToHTML`}
+	issues, err = validateTemplateDir(root, `template`, cfg)
+	assert.NoError(t, err)
+	assert.Len(t, issues, 1)
+	assert.Equal(t, `template/common/header.html`, issues[0].File)
+	assert.Equal(t, `function "Include" not defined`, issues[0].Message)
+}
+
+func TestCheckTemplates(t *testing.T) {
+	original := p.Clone()
+	defer func() {
+		p = original
+	}()
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		`template/index.html`: `{{.Title}}`,
+		`vendor/github.com/nging-plugins/collector/template/collector/index.html`: "\n{{if}}",
+	})
+	p.ProjectPath = root
+	p.AssetDirs = nil
+	p.AutoDiscoveryMiscDir = false
+	p.TemplateCheck = TemplateCheckConfig{}
+	buf := bytes.NewBuffer(nil)
+	valid, err := checkTemplates(context.Background(), map[string][]string{
+		`linux`: {`vendor/github.com/nging-plugins/collector/template/`},
+	}, buf)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	assert.Equal(t, "[template]\t:\t vendor/github.com/nging-plugins/collector/template/collector/index.html:2: missing value for if\n", buf.String())
+}